@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	db "github.com/apxxxxxxe/kanban.txt/internal/db"
+)
+
+func addCommand() *command {
+	c := &command{
+		name:  "add",
+		usage: `add [-date YYYY-MM-DD] "text +project @context due:YYYY-MM-DD rec:1w"`,
+	}
+	c.run = func(args []string) error {
+		fs := newFlagSet(c)
+		dateStr := fs.String("date", "", "created date of the task (default today)")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		input := strings.Join(fs.Args(), " ")
+		if strings.TrimSpace(input) == "" {
+			return errors.New("no task text given")
+		}
+
+		date, err := parseDate(*dateStr)
+		if err != nil {
+			return err
+		}
+
+		d, err := loadDatabase()
+		if err != nil {
+			return err
+		}
+
+		task, err := db.NewTask(input, "", date)
+		if err != nil {
+			return err
+		}
+		d.AddTask(task)
+
+		// RefreshProjects assigns recurrence IDs and saves the data
+		if err := d.RefreshProjects(dayOffset(date)); err != nil {
+			return err
+		}
+
+		fmt.Println(db.TaskString(task))
+		return nil
+	}
+	return c
+}
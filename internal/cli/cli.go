@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/1set/todotxt"
+	db "github.com/apxxxxxxe/kanban.txt/internal/db"
+	"github.com/apxxxxxxe/kanban.txt/pkg/util"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var ErrUnknownCommand = errors.New("unknown command")
+
+func commands() []*command {
+	return []*command{
+		addCommand(),
+		listCommand(),
+		startCommand(),
+		doneCommand(),
+		reopenCommand(),
+		moveCommand(),
+		timesheetCommand(),
+		statsCommand(),
+		archiveCommand(),
+	}
+}
+
+// Run executes the subcommand named by args[0] without launching the TUI.
+func Run(args []string) error {
+	if len(args) == 0 {
+		return ErrUnknownCommand
+	}
+	for _, c := range commands() {
+		if c.name == args[0] {
+			return c.run(args[1:])
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownCommand, args[0])
+}
+
+// PrintUsage writes the list of subcommands to w.
+func PrintUsage(w io.Writer) {
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands() {
+		fmt.Fprintf(w, "  %s\n", c.usage)
+	}
+}
+
+func newFlagSet(c *command) *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s\n", c.usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseDate returns today when s is empty
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return util.RemoveClockTime(time.Now()), nil
+	}
+	return time.ParseInLocation(todotxt.DateLayout, s, time.Local)
+}
+
+// dayOffset converts date into the day argument of db.Database.RefreshProjects
+func dayOffset(date time.Time) int {
+	today := util.RemoveClockTime(time.Now())
+	return int(math.Round(util.RemoveClockTime(date).Sub(today).Hours() / 24))
+}
+
+// loadDatabase loads the data with the views and the columns of the config
+func loadDatabase() (*db.Database, error) {
+	config, err := db.LoadOrNewConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := db.ValidateColumns(config.Columns); err != nil {
+		return nil, err
+	}
+	views, err := db.ParseViews(config.Views)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &db.Database{Views: views, Columns: config.Columns, KeepDoneTasks: config.KeepDoneTasks, KeepDoneDays: config.KeepDoneDays}
+	if err := d.LoadData(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
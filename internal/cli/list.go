@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/1set/todotxt"
+	db "github.com/apxxxxxxe/kanban.txt/internal/db"
+	tsk "github.com/apxxxxxxe/kanban.txt/internal/task"
+)
+
+const (
+	formatText    = "text"
+	formatTodoTxt = "todotxt"
+	formatJSON    = "json"
+)
+
+type taskJSON struct {
+	Line          int      `json:"line,omitempty"`
+	Text          string   `json:"text"`
+	Raw           string   `json:"raw"`
+	Priority      string   `json:"priority,omitempty"`
+	Projects      []string `json:"projects"`
+	Contexts      []string `json:"contexts"`
+	CreatedDate   string   `json:"created_date,omitempty"`
+	DueDate       string   `json:"due_date,omitempty"`
+	CompletedDate string   `json:"completed_date,omitempty"`
+	Completed     bool     `json:"completed"`
+	Recurrence    string   `json:"rec,omitempty"`
+	RecurrenceID  string   `json:"recid,omitempty"`
+	Note          string   `json:"note,omitempty"`
+	StartDoing    string   `json:"doing,omitempty"`
+}
+
+type columnJSON struct {
+	Name  string     `json:"name"`
+	Tasks []taskJSON `json:"tasks"`
+}
+
+type listJSON struct {
+	Project string       `json:"project"`
+	Date    string       `json:"date"`
+	Columns []columnJSON `json:"columns"`
+}
+
+func listCommand() *command {
+	c := &command{
+		name:  "list",
+		usage: "list [-date YYYY-MM-DD] [-project NAME] [-column NAME,...] [-format text|todotxt|json]",
+	}
+	c.run = func(args []string) error {
+		fs := newFlagSet(c)
+		dateStr := fs.String("date", "", "date of the board (default today)")
+		projectName := fs.String("project", db.AllTasks, "project or view to list")
+		columnStr := fs.String("column", "", "comma separated columns to list (default all)")
+		format := fs.String("format", formatText, "output format: text, todotxt or json")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		date, err := parseDate(*dateStr)
+		if err != nil {
+			return err
+		}
+
+		d, err := loadDatabase()
+		if err != nil {
+			return err
+		}
+		if err := d.BuildProjects(dayOffset(date)); err != nil {
+			return err
+		}
+
+		project := d.GetProject(*projectName)
+		if project == nil {
+			project = &db.Project{ProjectName: *projectName}
+		}
+
+		names := []string{}
+		for _, c := range d.GetColumns() {
+			names = append(names, c.Name)
+		}
+		if *columnStr != "" {
+			names = strings.Split(*columnStr, ",")
+		}
+
+		columns := []columnJSON{}
+		for _, name := range names {
+			index, err := columnIndex(d, name)
+			if err != nil {
+				return err
+			}
+			column := columnJSON{Name: d.GetColumns()[index].Name, Tasks: []taskJSON{}}
+			var tasks db.TaskReferences
+			if index < len(project.Columns) {
+				tasks = project.Columns[index]
+			}
+			for _, t := range tasks {
+				column.Tasks = append(column.Tasks, newTaskJSON(d, t))
+			}
+			columns = append(columns, column)
+		}
+
+		switch *format {
+		case formatText:
+			for i, column := range columns {
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Printf("%s (%d)\n", column.Name, len(column.Tasks))
+				for _, t := range column.Tasks {
+					fmt.Println("  " + taskLine(t))
+				}
+			}
+		case formatTodoTxt:
+			for _, column := range columns {
+				for _, t := range column.Tasks {
+					fmt.Println(t.Raw)
+				}
+			}
+		case formatJSON:
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(listJSON{
+				Project: project.ProjectName,
+				Date:    date.Format(todotxt.DateLayout),
+				Columns: columns,
+			})
+		default:
+			return fmt.Errorf("unknown format: %s", *format)
+		}
+		return nil
+	}
+	return c
+}
+
+// columnIndex returns the index of the column named name, ignoring case
+func columnIndex(d *db.Database, name string) (int, error) {
+	name = strings.TrimSpace(name)
+	for i, c := range d.GetColumns() {
+		if strings.EqualFold(c.Name, name) {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("unknown column: %s", name)
+}
+
+func dateToStr(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(todotxt.DateLayout)
+}
+
+func newTaskJSON(d *db.Database, t *todotxt.Task) taskJSON {
+	projects := []string{}
+	for _, p := range t.Projects {
+		if p != db.NoProject {
+			projects = append(projects, p)
+		}
+	}
+	return taskJSON{
+		Line:          t.ID,
+		Text:          t.Todo,
+		Raw:           db.TaskString(t),
+		Priority:      t.Priority,
+		Projects:      projects,
+		Contexts:      d.TaskContexts(t),
+		CreatedDate:   dateToStr(t.CreatedDate),
+		DueDate:       dateToStr(t.DueDate),
+		CompletedDate: dateToStr(t.CompletedDate),
+		Completed:     t.Completed,
+		Recurrence:    t.AdditionalTags[tsk.KeyRec],
+		RecurrenceID:  t.AdditionalTags[tsk.KeyRecID],
+		Note:          tsk.Note(t),
+		StartDoing:    t.AdditionalTags[tsk.KeyStartDoing],
+	}
+}
+
+// taskLine is the human readable form of t used by the text format
+func taskLine(t taskJSON) string {
+	fields := []string{}
+	if t.Priority != "" {
+		fields = append(fields, "("+t.Priority+")")
+	}
+	fields = append(fields, t.Text)
+	for _, p := range t.Projects {
+		fields = append(fields, "+"+p)
+	}
+	for _, c := range t.Contexts {
+		fields = append(fields, "@"+c)
+	}
+	if t.DueDate != "" {
+		fields = append(fields, "due:"+t.DueDate)
+	}
+	if t.Recurrence != "" {
+		fields = append(fields, "rec:"+t.Recurrence)
+	}
+	return strings.Join(fields, " ")
+}
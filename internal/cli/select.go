@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/1set/todotxt"
+	db "github.com/apxxxxxxe/kanban.txt/internal/db"
+	tsk "github.com/apxxxxxxe/kanban.txt/internal/task"
+)
+
+// selectTask finds the living task identified by id.
+// id is a line number of todo.txt, a recurrence ID, or words contained in the task text.
+// The occurrences generated for the day have no line number until they are saved.
+func selectTask(d *db.Database, id string) (*todotxt.Task, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return nil, fmt.Errorf("no task identifier given")
+	}
+
+	if line, err := strconv.Atoi(id); err == nil && line > 0 {
+		for _, t := range d.LivingTasks {
+			if t.ID == line {
+				return t, nil
+			}
+		}
+	}
+
+	for _, t := range d.LivingTasks {
+		if t.AdditionalTags[tsk.KeyRecID] == id {
+			return t, nil
+		}
+	}
+
+	words := strings.Fields(strings.ToLower(id))
+	candidates := db.TaskReferences{}
+	for _, t := range d.LivingTasks {
+		text := strings.ToLower(t.Todo)
+		if text == strings.ToLower(id) {
+			// an exact match wins over partial ones
+			return t, nil
+		}
+		matched := true
+		for _, w := range words {
+			if !strings.Contains(text, w) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			candidates.AddTask(t)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("task not found: %s", id)
+	case 1:
+		return candidates[0], nil
+	default:
+		lines := []string{}
+		for _, t := range candidates {
+			if t.ID == 0 {
+				lines = append(lines, "  -: "+db.TaskString(t))
+			} else {
+				lines = append(lines, fmt.Sprintf("  %d: %s", t.ID, db.TaskString(t)))
+			}
+		}
+		return nil, fmt.Errorf("ambiguous task %q matches:\n%s", id, strings.Join(lines, "\n"))
+	}
+}
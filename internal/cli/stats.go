@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/1set/todotxt"
+	db "github.com/apxxxxxxe/kanban.txt/internal/db"
+)
+
+type percentilesJSON struct {
+	Count int `json:"count"`
+	P50   int `json:"p50"`
+	P85   int `json:"p85"`
+	P95   int `json:"p95"`
+}
+
+type throughputJSON struct {
+	Week      string `json:"week"`
+	Completed int    `json:"completed"`
+}
+
+type agingJSON struct {
+	Task taskJSON `json:"task"`
+	Days int      `json:"days"`
+}
+
+type statsJSON struct {
+	Project    string           `json:"project"`
+	LeadTime   percentilesJSON  `json:"lead_time_days"`
+	CycleTime  percentilesJSON  `json:"cycle_time_days"`
+	Throughput []throughputJSON `json:"weekly_throughput"`
+	Aging      []agingJSON      `json:"aging"`
+}
+
+func statsCommand() *command {
+	c := &command{
+		name:  "stats",
+		usage: "stats [-project NAME] [-weeks N] [-format text|json]",
+	}
+	c.run = func(args []string) error {
+		fs := newFlagSet(c)
+		projectName := fs.String("project", "", "project or view to report (default all projects)")
+		weeks := fs.Int("weeks", 8, "number of the weeks of the throughput")
+		format := fs.String("format", formatText, "output format: text or json")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if *weeks < 1 {
+			return fmt.Errorf("-weeks must be positive")
+		}
+
+		d, err := loadDatabase()
+		if err != nil {
+			return err
+		}
+		if err := d.BuildProjects(0); err != nil {
+			return err
+		}
+
+		projects := []*db.Project{}
+		if *projectName != "" {
+			p := d.GetProject(*projectName)
+			if p == nil {
+				return fmt.Errorf("project not found: %s", *projectName)
+			}
+			projects = append(projects, p)
+		} else {
+			for _, p := range d.Projects {
+				if p.View == nil {
+					projects = append(projects, p)
+				}
+			}
+		}
+
+		now := time.Now()
+		reports := []statsJSON{}
+		for _, p := range projects {
+			m, err := d.FlowMetrics(p, now, *weeks)
+			if err != nil {
+				return err
+			}
+			reports = append(reports, newStatsJSON(d, m))
+		}
+
+		switch *format {
+		case formatText:
+			for i, r := range reports {
+				if i > 0 {
+					fmt.Println()
+				}
+				printStats(r)
+			}
+		case formatJSON:
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(reports)
+		default:
+			return fmt.Errorf("unknown format: %s", *format)
+		}
+		return nil
+	}
+	return c
+}
+
+func newStatsJSON(d *db.Database, m *db.FlowMetrics) statsJSON {
+	s := statsJSON{
+		Project:    m.ProjectName,
+		LeadTime:   percentilesJSON(m.LeadTime),
+		CycleTime:  percentilesJSON(m.CycleTime),
+		Throughput: []throughputJSON{},
+		Aging:      []agingJSON{},
+	}
+	for _, w := range m.Throughput {
+		s.Throughput = append(s.Throughput, throughputJSON{Week: w.Week.Format(todotxt.DateLayout), Completed: w.Completed})
+	}
+	for _, a := range m.Aging {
+		s.Aging = append(s.Aging, agingJSON{Task: newTaskJSON(d, a.Task), Days: a.Days})
+	}
+	return s
+}
+
+func percentilesText(p percentilesJSON) string {
+	if p.Count == 0 {
+		return "no data"
+	}
+	return fmt.Sprintf("p50 %dd, p85 %dd, p95 %dd (%d tasks)", p.P50, p.P85, p.P95, p.Count)
+}
+
+func printStats(s statsJSON) {
+	fmt.Println(s.Project)
+	fmt.Println("  Lead time:  " + percentilesText(s.LeadTime))
+	fmt.Println("  Cycle time: " + percentilesText(s.CycleTime))
+
+	counts := []string{}
+	for _, w := range s.Throughput {
+		counts = append(counts, fmt.Sprint(w.Completed))
+	}
+	fmt.Printf("  Throughput: %s (weekly since %s)\n", strings.Join(counts, " "), s.Throughput[0].Week)
+
+	if len(s.Aging) == 0 {
+		return
+	}
+	fmt.Println("  Aging:")
+	for _, a := range s.Aging {
+		fmt.Printf("    %3dd %s\n", a.Days, taskLine(a.Task))
+	}
+}
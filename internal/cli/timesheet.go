@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/1set/todotxt"
+	db "github.com/apxxxxxxe/kanban.txt/internal/db"
+	tsk "github.com/apxxxxxxe/kanban.txt/internal/task"
+)
+
+const (
+	groupByProject = "project"
+	groupByContext = "context"
+	noContext      = "NoContext"
+)
+
+type timesheetGroupJSON struct {
+	Name     string `json:"name"`
+	Minutes  int    `json:"minutes"`
+	Duration string `json:"duration"`
+}
+
+type timesheetJSON struct {
+	From         string               `json:"from"`
+	To           string               `json:"to"`
+	By           string               `json:"by"`
+	Groups       []timesheetGroupJSON `json:"groups"`
+	TotalMinutes int                  `json:"total_minutes"`
+}
+
+func timesheetCommand() *command {
+	c := &command{
+		name:  "timesheet",
+		usage: "timesheet [-from YYYY-MM-DD] [-to YYYY-MM-DD] [-by project|context] [-format text|json]",
+	}
+	c.run = func(args []string) error {
+		fs := newFlagSet(c)
+		fromStr := fs.String("from", "", "first day of the report (default 6 days before -to)")
+		toStr := fs.String("to", "", "last day of the report (default today)")
+		by := fs.String("by", groupByProject, "group the time by project or context")
+		format := fs.String("format", formatText, "output format: text or json")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if *by != groupByProject && *by != groupByContext {
+			return fmt.Errorf("unknown group: %s", *by)
+		}
+
+		to, err := parseDate(*toStr)
+		if err != nil {
+			return err
+		}
+		from := to.AddDate(0, 0, -6)
+		if *fromStr != "" {
+			if from, err = parseDate(*fromStr); err != nil {
+				return err
+			}
+		}
+		if to.Before(from) {
+			return fmt.Errorf("-to is before -from")
+		}
+
+		d, err := loadDatabase()
+		if err != nil {
+			return err
+		}
+
+		// done.txtに移ったタスクの時間も数える
+		done, err := d.DoneTasks()
+		if err != nil {
+			return err
+		}
+		tasks := append(append(append(db.TaskReferences{}, d.LivingTasks...), d.HiddenTasks...), done...)
+
+		// 期間の終わりは最終日の翌日0時
+		groups, total := timesheet(d, tasks, from, to.AddDate(0, 0, 1), *by, time.Now())
+
+		report := timesheetJSON{
+			From:         from.Format(todotxt.DateLayout),
+			To:           to.Format(todotxt.DateLayout),
+			By:           *by,
+			Groups:       []timesheetGroupJSON{},
+			TotalMinutes: int(total.Minutes()),
+		}
+		for _, g := range groups {
+			report.Groups = append(report.Groups, timesheetGroupJSON{
+				Name:     g.name,
+				Minutes:  int(g.spent.Minutes()),
+				Duration: tsk.FormatDuration(g.spent),
+			})
+		}
+
+		switch *format {
+		case formatText:
+			fmt.Printf("%s - %s by %s\n", report.From, report.To, report.By)
+			width := len("Total")
+			for _, g := range report.Groups {
+				if len(g.Name) > width {
+					width = len(g.Name)
+				}
+			}
+			for _, g := range report.Groups {
+				fmt.Printf("  %-*s %8s\n", width, g.Name, g.Duration)
+			}
+			fmt.Printf("  %-*s %8s\n", width, "Total", tsk.FormatDuration(total))
+		case formatJSON:
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(report)
+		default:
+			return fmt.Errorf("unknown format: %s", *format)
+		}
+		return nil
+	}
+	return c
+}
+
+type timesheetGroup struct {
+	name  string
+	spent time.Duration
+}
+
+// timesheet sums the stints of tasks within [from, to) for each group.
+// A task in several groups counts in each of them but once in the total.
+func timesheet(d *db.Database, tasks db.TaskReferences, from, to time.Time, by string, now time.Time) ([]timesheetGroup, time.Duration) {
+	spent := map[string]time.Duration{}
+	var total time.Duration
+	for _, t := range tasks {
+		stints, err := tsk.Stints(t)
+		if err != nil {
+			continue
+		}
+
+		var taskSpent time.Duration
+		for _, s := range stints {
+			clipped := s
+			if clipped.End.IsZero() {
+				clipped.End = now
+			}
+			if clipped.Start.Before(from) {
+				clipped.Start = from
+			}
+			if clipped.End.After(to) {
+				clipped.End = to
+			}
+			taskSpent += clipped.Duration(now)
+		}
+		if taskSpent == 0 {
+			continue
+		}
+
+		total += taskSpent
+		for _, name := range groupNames(d, t, by) {
+			spent[name] += taskSpent
+		}
+	}
+
+	groups := []timesheetGroup{}
+	for name, spentTime := range spent {
+		groups = append(groups, timesheetGroup{name, spentTime})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].spent != groups[j].spent {
+			return groups[i].spent > groups[j].spent
+		}
+		return groups[i].name < groups[j].name
+	})
+	return groups, total
+}
+
+func groupNames(d *db.Database, t *todotxt.Task, by string) []string {
+	if by == groupByProject {
+		return db.ProjectNames(t)
+	}
+	contexts := []string{}
+	for _, c := range d.TaskContexts(t) {
+		if !containsName(contexts, c) {
+			contexts = append(contexts, c)
+		}
+	}
+	if len(contexts) == 0 {
+		return []string{noContext}
+	}
+	return contexts
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/1set/todotxt"
+	db "github.com/apxxxxxxe/kanban.txt/internal/db"
+)
+
+func transitionCommand(name, description string, fn func(*db.Database, *todotxt.Task, time.Time) error) *command {
+	return columnTransitionCommand(name, "", description, func(d *db.Database, t *todotxt.Task, date time.Time, _ string) error {
+		return fn(d, t, date)
+	})
+}
+
+// columnTransitionCommand is transitionCommand whose first argument is the column to move the task to
+func columnTransitionCommand(name, column, description string, fn func(*db.Database, *todotxt.Task, time.Time, string) error) *command {
+	usage := name + " [-date YYYY-MM-DD] "
+	if column != "" {
+		usage += column + " "
+	}
+	c := &command{
+		name:  name,
+		usage: usage + "LINE|RECID|TEXT" + "\t" + description,
+	}
+	c.run = func(args []string) error {
+		fs := newFlagSet(c)
+		dateStr := fs.String("date", "", "date of the transition (default today)")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		date, err := parseDate(*dateStr)
+		if err != nil {
+			return err
+		}
+		day := dayOffset(date)
+
+		d, err := loadDatabase()
+		if err != nil {
+			return err
+		}
+		// generate recurrent tasks of the day so that they can be selected
+		if err := d.BuildProjects(day); err != nil {
+			return err
+		}
+
+		rest := fs.Args()
+		columnName := ""
+		if column != "" {
+			if len(rest) == 0 {
+				return fmt.Errorf("no column given")
+			}
+			columnName, rest = rest[0], rest[1:]
+		}
+
+		task, err := selectTask(d, strings.Join(rest, " "))
+		if err != nil {
+			return err
+		}
+		if err := fn(d, task, date, columnName); err != nil {
+			return err
+		}
+
+		if err := d.RefreshProjects(day); err != nil {
+			return err
+		}
+
+		fmt.Println(db.TaskString(task))
+		return nil
+	}
+	return c
+}
+
+// moveTask moves t to the column at index, refusing to start a blocked task
+// and warning about the WIP limits
+func moveTask(d *db.Database, t *todotxt.Task, index int, date time.Time) error {
+	if d.IsWorkColumn(index) {
+		if blockers := d.Blockers(t); len(blockers) > 0 {
+			texts := []string{}
+			for _, b := range blockers {
+				texts = append(texts, b.Todo)
+			}
+			return fmt.Errorf("blocked by %s", strings.Join(texts, ", "))
+		}
+	}
+	if err := d.CheckWIPLimit(t, index); err != nil {
+		if d.GetColumns()[index].StrictLimit {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "warning:", err)
+	}
+	d.MoveTask(t, index, date)
+	return nil
+}
+
+func startCommand() *command {
+	return transitionCommand("start", "move a task to Doing", func(d *db.Database, t *todotxt.Task, date time.Time) error {
+		index := d.DoingColumn()
+		if index < 0 {
+			return errors.New("the board has no @doing column")
+		}
+		return moveTask(d, t, index, date)
+	})
+}
+
+func doneCommand() *command {
+	return transitionCommand("done", "move a task to Done", func(d *db.Database, t *todotxt.Task, date time.Time) error {
+		return moveTask(d, t, d.DoneColumn(), date)
+	})
+}
+
+func reopenCommand() *command {
+	return transitionCommand("reopen", "move a task back to Todo", func(d *db.Database, t *todotxt.Task, date time.Time) error {
+		return moveTask(d, t, d.DefaultColumn(), date)
+	})
+}
+
+func moveCommand() *command {
+	return columnTransitionCommand("move", "COLUMN", "move a task to the column", func(d *db.Database, t *todotxt.Task, date time.Time, name string) error {
+		index, err := columnIndex(d, name)
+		if err != nil {
+			return err
+		}
+		return moveTask(d, t, index, date)
+	})
+}
+
+func archiveCommand() *command {
+	return transitionCommand("archive", "archive a task, or its series if it recurs", func(d *db.Database, t *todotxt.Task, _ time.Time) error {
+		d.ArchiveTask(t)
+		return nil
+	})
+}
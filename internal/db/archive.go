@@ -0,0 +1,116 @@
+package db
+
+import (
+	"time"
+
+	"github.com/1set/todotxt"
+	tsk "github.com/apxxxxxxe/kanban.txt/internal/task"
+)
+
+// ArchivedEntry is a recurring series or a single task in ArchivedTasks
+type ArchivedEntry struct {
+	// the recid of the series or the id of the task
+	Key string
+	// the latest task of the entry, nil if none is left
+	Task *todotxt.Task
+	// the completed date of the latest completed task, zero if none is completed
+	LastCompleted time.Time
+	// the number of the tasks of the entry
+	Count int
+}
+
+// archiveKey returns the key of t in ArchivedTasks, the recid of its series or its id
+func archiveKey(t *todotxt.Task) (string, bool) {
+	if recID, ok := t.AdditionalTags[tsk.KeyRecID]; ok {
+		return recID, true
+	}
+	id, ok := t.AdditionalTags[tsk.KeyID]
+	return id, ok && id != ""
+}
+
+// IsArchived reports whether t is hidden from the board by ArchivedTasks
+func (d *Database) IsArchived(t *todotxt.Task) bool {
+	key, ok := archiveKey(t)
+	return ok && containsString(d.ArchivedTasks, key)
+}
+
+// ArchivedEntries returns the archived entries in the order of archiving
+// with their tasks resolved from the living and the hidden tasks.
+func (d *Database) ArchivedEntries() []*ArchivedEntry {
+	list := []*ArchivedEntry{}
+	seen := map[string]bool{}
+	for _, key := range d.ArchivedTasks {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		e := &ArchivedEntry{Key: key}
+		for _, t := range d.archivedTasks(key) {
+			e.Count++
+			if e.Task == nil || t.CreatedDate.After(e.Task.CreatedDate) {
+				e.Task = t
+			}
+			if t.Completed && t.CompletedDate.After(e.LastCompleted) {
+				e.LastCompleted = t.CompletedDate
+			}
+		}
+		list = append(list, e)
+	}
+	return list
+}
+
+// archivedTasks returns the living and the hidden tasks archived by key
+func (d *Database) archivedTasks(key string) TaskReferences {
+	tasks := TaskReferences{}
+	for _, t := range append(append(TaskReferences{}, d.LivingTasks...), d.HiddenTasks...) {
+		if k, ok := archiveKey(t); ok && k == key {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks
+}
+
+// Unarchive shows the tasks archived by key on the board again
+func (d *Database) Unarchive(key string) {
+	d.ArchivedTasks = removeString(d.ArchivedTasks, key)
+}
+
+// PurgeArchived deletes every task archived by key as well as its archive entry
+func (d *Database) PurgeArchived(key string) {
+	isArchived := func(t todotxt.Task) bool {
+		k, ok := archiveKey(&t)
+		return ok && k == key
+	}
+	d.LivingTasks = *d.LivingTasks.Filter(todotxt.FilterNot(isArchived))
+	d.HiddenTasks = *d.HiddenTasks.Filter(todotxt.FilterNot(isArchived))
+	d.Unarchive(key)
+}
+
+// ArchiveDoneTasks archives the tasks of p completed before date and returns the number of them.
+// Recurring tasks are left since archiving one hides the open tasks of its series too.
+// The archived tasks are still moved to done.txt by the rotation of the completed tasks.
+func (d *Database) ArchiveDoneTasks(p *Project, date time.Time) int {
+	count := 0
+	for _, t := range projectTasks(p, date, append(append(TaskReferences{}, d.LivingTasks...), d.HiddenTasks...)) {
+		if !t.Completed || !t.CompletedDate.Before(date) || d.IsArchived(t) {
+			continue
+		}
+		if _, ok := t.AdditionalTags[tsk.KeyRecID]; ok {
+			continue
+		}
+		d.ArchiveTask(t)
+		count++
+	}
+	return count
+}
+
+func removeString(a []string, s string) []string {
+	result := []string{}
+	for _, v := range a {
+		if v != s {
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,305 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/1set/todotxt"
+	tsk "github.com/apxxxxxxe/kanban.txt/internal/task"
+)
+
+// the context of the in-progress column, which records the doing: date
+const doingContext = "doing"
+
+// Column is a column of the board.
+// A task belongs to the column by the context or the status: tag of it,
+// to the Done column when it is completed,
+// and to the default column, which has neither, otherwise.
+type Column struct {
+	Name    string `json:"name"`
+	Context string `json:"context,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Done    bool   `json:"done,omitempty"`
+	// the work-in-progress limit of the column, unlimited if 0
+	Limit int `json:"limit,omitempty"`
+	// project name to the work-in-progress limit of the project in the column
+	ProjectLimits map[string]int `json:"projectLimits,omitempty"`
+	// refuse to move a task into the column over the limits instead of warning
+	StrictLimit bool `json:"strictLimit,omitempty"`
+}
+
+// DefaultColumns is the classic Todo/Doing/Done board
+func DefaultColumns() []*Column {
+	return []*Column{
+		{Name: "Todo"},
+		{Name: "Doing", Context: doingContext},
+		{Name: "Done", Done: true},
+	}
+}
+
+// ColumnsOrDefault returns DefaultColumns if columns are not configured
+func ColumnsOrDefault(columns []*Column) []*Column {
+	if len(columns) == 0 {
+		return DefaultColumns()
+	}
+	return columns
+}
+
+// ValidateColumns checks the columns of the config
+func ValidateColumns(columns []*Column) error {
+	columns = ColumnsOrDefault(columns)
+	names := map[string]bool{}
+	defaults, dones := 0, 0
+	for _, c := range columns {
+		if c.Name == "" {
+			return fmt.Errorf("invalid column: empty name")
+		}
+		if names[c.Name] {
+			return fmt.Errorf("duplicate column name: %s", c.Name)
+		}
+		names[c.Name] = true
+		if c.Limit < 0 {
+			return fmt.Errorf("column %s: negative limit", c.Name)
+		}
+		for p, limit := range c.ProjectLimits {
+			if limit < 0 {
+				return fmt.Errorf("column %s: negative limit of %s", c.Name, p)
+			}
+		}
+
+		kinds := 0
+		if c.Context != "" {
+			kinds++
+		}
+		if c.Status != "" {
+			kinds++
+		}
+		if c.Done {
+			kinds++
+			dones++
+		}
+		switch kinds {
+		case 0:
+			defaults++
+		case 1:
+		default:
+			return fmt.Errorf("column %s: only one of context, status and done can be set", c.Name)
+		}
+	}
+	if defaults != 1 {
+		return fmt.Errorf("exactly one column must have neither context, status nor done")
+	}
+	if dones != 1 {
+		return fmt.Errorf("exactly one column must be done")
+	}
+	return nil
+}
+
+// GetColumns returns the columns of the board
+func (d *Database) GetColumns() []*Column {
+	return ColumnsOrDefault(d.Columns)
+}
+
+// ColumnIndex returns the index of the column which t belongs to
+func (d *Database) ColumnIndex(t *todotxt.Task) int {
+	columns := d.GetColumns()
+	defaultIndex := 0
+	for i, c := range columns {
+		switch {
+		case c.Done:
+			if t.Completed {
+				return i
+			}
+		case c.Context != "":
+			if !t.Completed && containsString(t.Contexts, c.Context) {
+				return i
+			}
+		case c.Status != "":
+			if !t.Completed && t.AdditionalTags[tsk.KeyStatus] == c.Status {
+				return i
+			}
+		default:
+			defaultIndex = i
+		}
+	}
+	return defaultIndex
+}
+
+// TaskContexts returns the contexts of t except the ones of the columns,
+// which are the state of the board rather than the contexts given by the user
+func (d *Database) TaskContexts(t *todotxt.Task) []string {
+	contexts := []string{}
+	for _, c := range t.Contexts {
+		if !d.isColumnContext(c) {
+			contexts = append(contexts, c)
+		}
+	}
+	return contexts
+}
+
+// isColumnContext reports whether context puts a task in one of the columns
+func (d *Database) isColumnContext(context string) bool {
+	for _, c := range d.GetColumns() {
+		if c.Context != "" && c.Context == context {
+			return true
+		}
+	}
+	return false
+}
+
+// SetTaskContexts replaces the contexts of t with contexts, keeping the ones of the columns
+func (d *Database) SetTaskContexts(t *todotxt.Task, contexts []string) {
+	result := []string{}
+	for _, c := range contexts {
+		// 列の移動は列の操作で行う
+		if !d.isColumnContext(c) {
+			result = append(result, c)
+		}
+	}
+	for _, c := range t.Contexts {
+		if d.isColumnContext(c) {
+			result = append(result, c)
+		}
+	}
+	if len(result) == 0 {
+		result = nil
+	}
+	t.Contexts = result
+}
+
+// DoneColumn returns the index of the Done column
+func (d *Database) DoneColumn() int {
+	for i, c := range d.GetColumns() {
+		if c.Done {
+			return i
+		}
+	}
+	return -1
+}
+
+// DoingColumn returns the index of the in-progress column, or -1 if the board has none
+func (d *Database) DoingColumn() int {
+	for i, c := range d.GetColumns() {
+		if c.Context == doingContext {
+			return i
+		}
+	}
+	return -1
+}
+
+// DefaultColumn returns the index of the column of new tasks
+func (d *Database) DefaultColumn() int {
+	for i, c := range d.GetColumns() {
+		if c.Context == "" && c.Status == "" && !c.Done {
+			return i
+		}
+	}
+	return 0
+}
+
+// IsWorkColumn reports whether the column at index is neither the default column nor the Done column.
+// A blocked task cannot enter it.
+func (d *Database) IsWorkColumn(index int) bool {
+	return index != d.DefaultColumn() && index != d.DoneColumn()
+}
+
+// ColumnLimit returns the number of the tasks of the project in the column at index and its limit.
+// The limit of the project takes precedence over that of the column, and 0 means unlimited.
+func (d *Database) ColumnLimit(projectName string, index int) (int, int) {
+	column := d.GetColumns()[index]
+	count := func(name string) int {
+		p := d.GetProject(name)
+		if p == nil || index >= len(p.Columns) {
+			return 0
+		}
+		return len(p.Columns[index])
+	}
+
+	if limit, ok := column.ProjectLimits[projectName]; ok && limit > 0 {
+		return count(projectName), limit
+	}
+	return count(AllTasks), column.Limit
+}
+
+// CheckWIPLimit returns an error if moving t into the column at index exceeds its limits
+func (d *Database) CheckWIPLimit(t *todotxt.Task, index int) error {
+	if d.ColumnIndex(t) == index {
+		return nil
+	}
+	column := d.GetColumns()[index]
+
+	for _, name := range ProjectNames(t) {
+		if _, ok := column.ProjectLimits[name]; !ok {
+			continue
+		}
+		if count, limit := d.ColumnLimit(name, index); limit > 0 && count >= limit {
+			return fmt.Errorf("%s of %s is at its WIP limit (%d/%d)", column.Name, name, count, limit)
+		}
+	}
+	if count, limit := d.ColumnLimit(AllTasks, index); limit > 0 && count >= limit {
+		return fmt.Errorf("%s is at its WIP limit (%d/%d)", column.Name, count, limit)
+	}
+	return nil
+}
+
+// MoveTask moves t to the column at index on date
+func (d *Database) MoveTask(t *todotxt.Task, index int, date time.Time) {
+	columns := d.GetColumns()
+	if index < 0 || index >= len(columns) {
+		return
+	}
+	column := columns[index]
+
+	// 他の列を表すコンテキストとstatusを外す
+	for _, c := range columns {
+		if c.Context != "" && c.Context != column.Context {
+			removeContext(t, c.Context)
+		}
+	}
+	if t.AdditionalTags != nil {
+		delete(t.AdditionalTags, tsk.KeyStatus)
+	}
+
+	switch {
+	case column.Done:
+		tsk.ToDone(t, date)
+	case column.Context == doingContext:
+		tsk.ToDoing(t, date)
+	case column.Context != "":
+		if !containsString(t.Contexts, column.Context) {
+			t.Contexts = append(t.Contexts, column.Context)
+		}
+		t.Reopen()
+	case column.Status != "":
+		if t.AdditionalTags == nil {
+			t.AdditionalTags = map[string]string{}
+		}
+		t.AdditionalTags[tsk.KeyStatus] = column.Status
+		t.Reopen()
+	default:
+		t.Reopen()
+	}
+
+	// 計測はDoingの間だけ
+	if column.Context != doingContext {
+		tsk.ClockOut(t, tsk.ClockTime(date, time.Now()))
+	}
+
+	// 作業開始日はDoingより前の列に戻したときだけ消す
+	if doing := d.DoingColumn(); doing >= 0 && index < doing && t.AdditionalTags != nil {
+		delete(t.AdditionalTags, tsk.KeyStartDoing)
+	}
+}
+
+func removeContext(t *todotxt.Task, context string) {
+	contexts := []string{}
+	for _, c := range t.Contexts {
+		if c != context {
+			contexts = append(contexts, c)
+		}
+	}
+	if len(contexts) == 0 {
+		contexts = nil
+	}
+	t.Contexts = contexts
+}
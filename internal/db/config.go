@@ -2,6 +2,8 @@ package db
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -10,6 +12,21 @@ import (
 
 type Config struct {
 	Color *ColorConfig `json:"color"`
+	// action name to key sequences, e.g. "deleteTask": ["dd"], "redo": ["<Ctrl-r>"]
+	Keys map[string][]string `json:"keys,omitempty"`
+	// virtual projects shown in the project pane, see View for the query
+	Views []*ViewConfig `json:"views,omitempty"`
+	// the columns of the board from left to right, the Todo/Doing/Done trio if empty
+	Columns []*Column `json:"columns,omitempty"`
+	// the completed tasks beyond this count are moved to done.txt, 100 if 0 and no limit if negative
+	KeepDoneTasks int `json:"keepDoneTasks,omitempty"`
+	// the completed tasks older than this number of days are moved to done.txt, no limit if 0
+	KeepDoneDays int `json:"keepDoneDays,omitempty"`
+}
+
+type ViewConfig struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
 }
 
 type ColorConfig struct {
@@ -20,6 +37,8 @@ type ColorConfig struct {
 	MinSaturatio int  `json:"minSaturatio"`
 	MaxLightness int  `json:"maxLightness"`
 	MinLightness int  `json:"minLightness"`
+	// project name to the color chosen in the color popup, e.g. "#ff8080"
+	ProjectColors map[string]string `json:"projectColors,omitempty"`
 }
 
 const (
@@ -32,13 +51,24 @@ const (
 	defaultMinLightness = 60
 )
 
-func LoadOrNewConfig() *Config {
-	config, err := loadConfig(filepath.Join(getDataPath(), ConfigFile))
-	if err != nil {
+// LoadOrNewConfig loads config.json, creating it with the defaults if it does not exist.
+// A config.json which cannot be read or parsed is reported and never overwritten
+// since the keys, the views and the columns in it are written by hand.
+func LoadOrNewConfig() (*Config, error) {
+	path := filepath.Join(getDataPath(), ConfigFile)
+	config, err := loadConfig(path)
+	if errors.Is(err, os.ErrNotExist) {
 		config = newConfig()
-		SaveConfig(config)
+		if err := SaveConfig(config); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
 	}
-	return config
+	if config.Color == nil {
+		config.Color = newConfig().Color
+	}
+	return config, nil
 }
 
 func SaveConfig(config *Config) error {
@@ -6,10 +6,13 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/1set/todotxt"
 	tsk "github.com/apxxxxxxe/kanban.txt/internal/task"
+	"github.com/apxxxxxxe/kanban.txt/pkg/util"
+	"github.com/google/uuid"
 )
 
 const (
@@ -27,7 +30,9 @@ const (
 const (
 	ArchiveFile = "archive.json"
 	ImportFile  = "todo.txt"
+	DoneFile    = "done.txt"
 	ConfigFile  = "config.json"
+	LockFile    = "kanban.lock"
 )
 
 var (
@@ -39,6 +44,29 @@ type Database struct {
 	HiddenTasks   TaskReferences
 	ArchivedTasks []string
 	Projects      []*Project
+	// virtual projects built after the real ones
+	Views []*View
+	// the ids of a dependency cycle found by BuildProjects, nil if there is none
+	DependencyCycle []string
+	// the columns of the board, DefaultColumns if nil
+	Columns []*Column
+	// the number of the completed tasks kept in todo.txt, KeepDoneTaskCount if 0 and no limit if negative
+	KeepDoneTasks int
+	// the completed tasks older than this number of days are moved to done.txt, no limit if 0
+	KeepDoneDays int
+
+	// the state of the data files when they were loaded or saved last
+	savedLines   []string
+	savedArchive []string
+	todoStamp    fileStamp
+	archiveStamp fileStamp
+	// the lines rotated out of todo.txt which the next save appends to done.txt
+	doneLines []string
+	// the number of each line appended to done.txt in this session
+	rotatedLines map[string]int
+	// the number of each line of done.txt restored into todo.txt by an undo,
+	// which is not appended again when it is rotated again
+	unrotatedLines map[string]int
 }
 
 type Archive struct {
@@ -46,10 +74,24 @@ type Archive struct {
 }
 
 type Project struct {
+	// nil unless the project is a view
+	View        *View
 	ProjectName string
-	TodoTasks   TaskReferences
-	DoingTasks  TaskReferences
-	DoneTasks   TaskReferences
+	// the tasks of each column in the order of Database.GetColumns
+	Columns []TaskReferences
+}
+
+// OpenTaskCount returns the number of the tasks not completed yet
+func (p *Project) OpenTaskCount() int {
+	count := 0
+	for _, tasks := range p.Columns {
+		for _, t := range tasks {
+			if !t.Completed {
+				count++
+			}
+		}
+	}
+	return count
 }
 
 func getDataPath() string {
@@ -102,6 +144,121 @@ func copyTask(t todotxt.Task) todotxt.Task {
 	return newTask
 }
 
+// NewTask parses the text typed by the user into a new task created at date.
+// If projectName is empty, projects written in the text are kept
+// and NoProject is used when there are none.
+func NewTask(input, projectName string, date time.Time) (*todotxt.Task, error) {
+	taskFields := []string{}
+	for _, field := range strings.Split(input, " ") {
+		if field == "" {
+			continue
+		}
+		taskFields = append(taskFields, tsk.ReplaceInvalidTag(field))
+	}
+	task, err := todotxt.ParseTask(strings.Join(taskFields, " "))
+	if err != nil {
+		return nil, err
+	}
+	if task.Todo == "" {
+		return nil, errors.New("empty task")
+	}
+
+	// add CreatedDate
+	task.CreatedDate = date
+
+	// add project
+	if projectName != "" {
+		task.Projects = []string{projectName}
+	} else if len(task.Projects) == 0 {
+		task.Projects = []string{NoProject}
+	}
+
+	// remove context "doing"
+	for i, context := range task.Contexts {
+		if context == "doing" {
+			task.Contexts = append(task.Contexts[:i], task.Contexts[i+1:]...)
+			break
+		}
+	}
+
+	// validate and identify recurrence
+	if err := tsk.ValidateRecurrence(task); err != nil {
+		return nil, err
+	}
+	if _, ok := task.AdditionalTags[tsk.KeyRec]; ok {
+		task.AdditionalTags[tsk.KeyRecID] = uuid.New().String()
+	}
+
+	return task, nil
+}
+
+// ParseTaskLine parses a whole line of todo.txt edited by the user.
+// Unlike NewTask, the dates and the contexts are kept as they are written.
+func ParseTaskLine(line string) (*todotxt.Task, error) {
+	line = strings.TrimSpace(line)
+	if strings.ContainsAny(line, "\r\n") {
+		return nil, errors.New("a task must be a single line")
+	}
+	task, err := todotxt.ParseTask(line)
+	if err != nil {
+		return nil, err
+	}
+	if task.Todo == "" {
+		return nil, errors.New("empty task")
+	}
+	if len(task.Projects) == 0 {
+		task.Projects = []string{NoProject}
+	}
+
+	if err := tsk.ValidateRecurrence(task); err != nil {
+		return nil, err
+	}
+	if _, err := tsk.Stints(task); err != nil {
+		return nil, err
+	}
+	if _, ok := task.AdditionalTags[tsk.KeyRec]; ok && task.AdditionalTags[tsk.KeyRecID] == "" {
+		task.AdditionalTags[tsk.KeyRecID] = uuid.New().String()
+	}
+	return task, nil
+}
+
+// TaskString returns t in todo.txt format as it is saved to the file.
+func TaskString(t *todotxt.Task) string {
+	ct := copyTask(*t)
+	stripNoProject(&ct)
+	return ct.String()
+}
+
+// stripNoProject removes NoProject, which is only for display, from the projects of t
+func stripNoProject(t *todotxt.Task) {
+	projects := []string{}
+	for _, p := range t.Projects {
+		if p != NoProject {
+			projects = append(projects, p)
+		}
+	}
+	if len(projects) == 0 {
+		projects = nil
+	}
+	t.Projects = projects
+}
+
+// ProjectNames returns the projects which t is shown under
+func ProjectNames(t *todotxt.Task) []string {
+	ct := copyTask(*t)
+	stripNoProject(&ct)
+	names := tsk.GetProjectNames(ct)
+	if len(names) == 0 {
+		return []string{NoProject}
+	}
+	return names
+}
+
+// AddTask adds t to the living tasks.
+func (d *Database) AddTask(t *todotxt.Task) {
+	d.LivingTasks.AddTask(t)
+}
+
 func sortTaskReferences(taskList TaskReferences) {
 	sort.Slice(taskList, func(i, j int) bool {
 		if taskList[i].Completed != taskList[j].Completed {
@@ -117,48 +274,82 @@ func sortTaskReferences(taskList TaskReferences) {
 }
 
 func (d *Database) SaveData() error {
+	unlock, err := lockDataDir()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return d.save()
+}
+
+func (d *Database) save() error {
 	return d.saveData(append(d.LivingTasks, d.HiddenTasks...), filepath.Join(getDataPath(), ImportFile))
 }
 
 func (d *Database) saveData(taskList TaskReferences, filePath string) error {
-	tasklist := TaskReferences{}
-	for _, t := range taskList {
-		ct := copyTask(*t)
-		tasklist = append(tasklist, &ct)
-	}
+	lines := taskLines(taskList)
 
-	for _, t := range tasklist {
-		if t.Projects != nil && len(t.Projects) > 0 && t.Projects[0] == NoProject {
-			t.Projects = nil
-		}
+	var sb strings.Builder
+	for _, line := range lines {
+		sb.WriteString(line + "\n")
 	}
 
-	sortTaskReferences(tasklist)
-
-	fp, err := os.Create(filePath)
+	b, err := json.MarshalIndent(Archive{ArchivedTasks: d.ArchivedTasks}, "", "  ")
 	if err != nil {
 		return err
 	}
-	defer fp.Close()
 
-	for _, task := range tasklist {
-		_, err := fp.WriteString(task.String() + "\n")
+	// WriteFilesAtomic renames the files in this order, and each rename is atomic but the batch is not.
+	// todo.txt goes last so that a crash between the renames leaves the rotated lines in both todo.txt and done.txt
+	// rather than in neither, and the tasks archived since the last save hidden rather than shown again.
+	files := []util.FileContent{}
+	if len(d.doneLines) > 0 {
+		done, err := d.doneFileContent()
 		if err != nil {
 			return err
 		}
+		files = append(files, util.FileContent{Path: filepath.Join(getDataPath(), DoneFile), Data: done})
 	}
-
-	b, err := json.MarshalIndent(Archive{ArchivedTasks: d.ArchivedTasks}, "", "  ")
-	if err != nil {
-		return err
-	}
-	if err := os.WriteFile(filepath.Join(getDataPath(), ArchiveFile), b, 0644); err != nil {
+	files = append(files,
+		util.FileContent{Path: filepath.Join(getDataPath(), ArchiveFile), Data: b},
+		util.FileContent{Path: filePath, Data: []byte(sb.String())},
+	)
+	if err := util.WriteFilesAtomic(files, 0644); err != nil {
 		return err
 	}
 
+	if d.rotatedLines == nil {
+		d.rotatedLines = map[string]int{}
+	}
+	for _, line := range d.doneLines {
+		d.rotatedLines[line]++
+	}
+	d.doneLines = nil
+	d.markSaved(lines, d.ArchivedTasks)
 	return nil
 }
 
+// taskLines returns the sorted lines of todo.txt which represent taskList
+func taskLines(taskList TaskReferences) []string {
+	tasklist := TaskReferences{}
+	for _, t := range taskList {
+		ct := copyTask(*t)
+		tasklist = append(tasklist, &ct)
+	}
+
+	for _, t := range tasklist {
+		stripNoProject(t)
+	}
+
+	sortTaskReferences(tasklist)
+
+	lines := []string{}
+	for _, task := range tasklist {
+		lines = append(lines, task.String())
+	}
+	return lines
+}
+
 func comparePriority(p1, p2 string) bool {
 	if p1 == "" {
 		return false
@@ -205,6 +396,7 @@ func (d *Database) LoadData() error {
 	}
 	d.ArchivedTasks = archive.ArchivedTasks
 
+	d.markSaved(taskLines(allTasks), d.ArchivedTasks)
 	return nil
 }
 
@@ -219,6 +411,11 @@ func (d *Database) loadData(filePath string) (TaskReferences, error) {
 		return taskList, nil
 	}
 
+	return toTaskReferences(tmpList), nil
+}
+
+func toTaskReferences(tmpList todotxt.TaskList) TaskReferences {
+	taskList := TaskReferences{}
 	for i := range tmpList {
 		task := &tmpList[i]
 		if task.Projects == nil || len(task.Projects) == 0 {
@@ -240,7 +437,7 @@ func (d *Database) loadData(filePath string) (TaskReferences, error) {
 
 	sortTaskReferences(taskList)
 
-	return taskList, nil
+	return taskList
 }
 
 func makeTaskMap(taskList TaskReferences, keyFunc func(todotxt.Task) string) map[string]*todotxt.Task {
@@ -262,12 +459,40 @@ func makeTaskMap(taskList TaskReferences, keyFunc func(todotxt.Task) string) map
 	return taskMap
 }
 
+// ArchiveTask hides t from the board.
+// A recurring task archives its whole series, and the others are archived by their ids.
 func (d *Database) ArchiveTask(t *todotxt.Task) {
-	v, ok := t.AdditionalTags[tsk.KeyRecID]
+	if _, ok := t.AdditionalTags[tsk.KeyRec]; ok && t.AdditionalTags[tsk.KeyRecID] == "" {
+		// 手で書かれた繰り返しタスクは系列ごとアーカイブする
+		t.AdditionalTags[tsk.KeyRecID] = uuid.New().String()
+	}
+	key, ok := archiveKey(t)
 	if !ok {
-		panic("recurrence id not found: " + t.String())
+		key = d.EnsureID(t)
+	}
+	if !containsString(d.ArchivedTasks, key) {
+		d.ArchivedTasks = append(d.ArchivedTasks, key)
+	}
+}
+
+// SeriesCount returns the number of tasks in the recurring series of t.
+func (d *Database) SeriesCount(t *todotxt.Task) int {
+	recID, ok := t.AdditionalTags[tsk.KeyRecID]
+	if !ok {
+		return 0
+	}
+	count := 0
+	for _, task := range append(append(TaskReferences{}, d.LivingTasks...), d.HiddenTasks...) {
+		if task.AdditionalTags[tsk.KeyRecID] == recID {
+			count++
+		}
 	}
-	d.ArchivedTasks = append(d.ArchivedTasks, v)
+	return count
+}
+
+// Occurrences returns up to n next dates when the series of t opens a new task.
+func (d *Database) Occurrences(t *todotxt.Task, n int) ([]time.Time, error) {
+	return tsk.Occurrences(t, n, d.SeriesCount(t))
 }
 
 func (d *Database) recurrentTasks(tasks *TaskReferences, day int) error {
@@ -293,19 +518,37 @@ func (d *Database) recurrentTasks(tasks *TaskReferences, day int) error {
 	}
 	sortTaskReferences(recurrenceCandidates)
 
+	// 系列ごとのタスク数
+	seriesCount := map[string]int{}
+	for _, t := range *tasks {
+		if recID, ok := t.AdditionalTags[tsk.KeyRecID]; ok {
+			seriesCount[recID]++
+		}
+	}
+
 	for i := range recurrenceCandidates {
 		t := recurrenceCandidates[i]
 		if _, ok := t.AdditionalTags[tsk.KeyRec]; ok {
-			nextTime, err := tsk.ParseRecurrence(t)
+			nextTime, err := tsk.NextOccurrence(t)
 			if err != nil {
-				return err
+				// 不正な繰り返しは入力時に通知されるため、ここでは繰り返さない
+				continue
+			}
+			if tsk.IsRecurrenceEnded(t, nextTime, seriesCount[t.AdditionalTags[tsk.KeyRecID]]) {
+				continue
 			}
 			// nextTimeを経過しているかどうか
 			if nextTime.Before(date) {
 				newTask := copyTask(*t)
 				newTask.Reopen()
 				delete(newTask.AdditionalTags, tsk.KeyStartDoing)
+				delete(newTask.AdditionalTags, tsk.KeyClock)
+				// 新しいタスクのIDは必要になったときに付与する
+				delete(newTask.AdditionalTags, tsk.KeyID)
+				// 保存されるまでファイル上の行を持たないので、元のタスクの行番号は引き継がない
+				newTask.ID = 0
 				newTask.CreatedDate = date
+				tsk.PruneOccurrenceTags(&newTask, date)
 				tasks.AddTask(&newTask)
 			}
 		}
@@ -352,12 +595,8 @@ func timeToDate(t *time.Time) time.Time {
 
 func filterArchivedTasks(archivedTasks []string) todotxt.Predicate {
 	return func(t todotxt.Task) bool {
-		for _, key := range archivedTasks {
-			if v, ok := t.AdditionalTags[tsk.KeyRecID]; ok && v == key {
-				return true
-			}
-		}
-		return false
+		key, ok := archiveKey(&t)
+		return ok && containsString(archivedTasks, key)
 	}
 }
 
@@ -376,10 +615,9 @@ func filterCompareDate(date time.Time) todotxt.Predicate {
 	return func(t todotxt.Task) bool {
 		taskMakedDoing := time.Time{}
 		if v, ok := t.AdditionalTags[tsk.KeyStartDoing]; ok {
-			var err error
-			taskMakedDoing, err = time.Parse(todotxt.DateLayout, v)
-			if err != nil {
-				panic(err)
+			// 不正な日付は無視する
+			if d, err := time.Parse(todotxt.DateLayout, v); err == nil {
+				taskMakedDoing = d
 			}
 		}
 		taskMakedDoing = timeToDate(&taskMakedDoing)
@@ -418,15 +656,46 @@ func uniqueTaskReferences(tasks TaskReferences) TaskReferences {
 	return unique
 }
 
+// 0. 他のプログラムによるディスク上の変更をマージ
 // 1. allTasksにLivingとHiddenを統合
 // 2. RecIDを持たないタスクにRecIDを付与
 // 3. 繰り返しタスクを生成
 // 4. プロジェクトごとにタスクを分類
 // 5. タスクをプロジェクトごとに分類
 // 6. タスクをLivingとHiddenに分類
-// 7. データを保存
+// 7. 依存関係の循環を検出
+// 8. 古い完了済みタスクをdone.txtへ移動
+// 9. データを保存
 
 func (d *Database) RefreshProjects(day int) error {
+	unlock, err := lockDataDir()
+	if err != nil {
+		// 保存できなくてもメモリ上の変更はボードに反映する
+		if buildErr := d.BuildProjects(day); buildErr != nil {
+			return buildErr
+		}
+		return err
+	}
+	defer unlock()
+
+	// do not overwrite the changes made by another program
+	syncErr := d.SyncWithDisk()
+	if syncErr == nil {
+		// ボードに残らないよう振り分ける前に移動する
+		d.rotateDoneTasks(time.Now())
+	}
+	if err := d.BuildProjects(day); err != nil {
+		return err
+	}
+	if syncErr != nil {
+		return syncErr
+	}
+	return d.save()
+}
+
+// BuildProjects is RefreshProjects without saving the data.
+// It is used to view the board of an arbitrary day.
+func (d *Database) BuildProjects(day int) error {
 	allTasks := append(d.LivingTasks, d.HiddenTasks...)
 	sortTaskReferences(allTasks)
 	recIDMap := map[string]string{}
@@ -451,69 +720,39 @@ func (d *Database) RefreshProjects(day int) error {
 	sortTaskReferences(allTasks)
 
 	d.Projects = []*Project{}
+	d.DependencyCycle = nil
 
 	if len(allTasks) == 0 {
 		return nil
 	}
 
-	const (
-		todo  = "todo"
-		doing = "doing"
-		done  = "done"
-	)
-
-	getTodoTasks := func(tasklist TaskReferences, day int) TaskReferences {
-		date := time.Now().AddDate(0, 0, day)
-		return *tasklist.Filter(todotxt.FilterNotCompleted).
-			Filter(todotxt.FilterNot(todotxt.FilterByContext("doing"))).
-			Filter(filterCompareDate(date)).
-			Filter(todotxt.FilterNot(filterArchivedTasks(d.ArchivedTasks)))
-	}
-
-	getDoingTasks := func(tasklist TaskReferences, day int) TaskReferences {
-		date := time.Now().AddDate(0, 0, day)
-		return *tasklist.Filter(todotxt.FilterNotCompleted).
-			Filter(todotxt.FilterByContext("doing")).
-			Filter(filterCompareDate(date)).
-			Filter(todotxt.FilterNot(filterArchivedTasks(d.ArchivedTasks)))
-	}
+	date := time.Now().AddDate(0, 0, day)
+	visibleTasks := *allTasks.Filter(filterCompareDate(date)).
+		Filter(todotxt.FilterNot(filterArchivedTasks(d.ArchivedTasks)))
 
-	getDoneTasks := func(tasklist TaskReferences, day int) TaskReferences {
-		date := time.Now().AddDate(0, 0, day)
-		tasks := *tasklist.Filter(todotxt.FilterCompleted).
-			Filter(todotxt.FilterNot(todotxt.FilterByContext("doing"))).
-			Filter(filterCompareDate(date)).
-			Filter(todotxt.FilterNot(filterArchivedTasks(d.ArchivedTasks)))
-		sort.Slice(tasks, func(i, j int) bool {
-			return tasks[i].CompletedDate.After(tasks[j].CompletedDate)
-		})
-		return tasks
-	}
+	// 完了済みのタスクは完了日の新しい順に並べる
+	sort.SliceStable(visibleTasks, func(i, j int) bool {
+		return visibleTasks[i].CompletedDate.After(visibleTasks[j].CompletedDate)
+	})
 
-	list := map[string]func(TaskReferences, int) TaskReferences{
-		todo:  getTodoTasks,
-		doing: getDoingTasks,
-		done:  getDoneTasks,
+	columnCount := len(d.GetColumns())
+	newProject := func(name string) *Project {
+		return &Project{ProjectName: name, Columns: make([]TaskReferences, columnCount)}
 	}
 
 	projectList := map[string]*Project{}
-	for key, fn := range list {
-		for _, task := range fn(allTasks, day) {
-			projectName := tsk.GetProjectName(*task)
+	allTaskProject := newProject(AllTasks)
+	for _, task := range visibleTasks {
+		column := d.ColumnIndex(task)
+		allTaskProject.Columns[column].AddTask(task)
+		// 複数のプロジェクトに属するタスクはそれぞれに表示する
+		for _, projectName := range ProjectNames(task) {
 			project, ok := projectList[projectName]
 			if !ok {
-				project = &Project{ProjectName: projectName}
+				project = newProject(projectName)
 				projectList[projectName] = project
 			}
-
-			switch key {
-			case todo:
-				project.TodoTasks.AddTask(task)
-			case doing:
-				project.DoingTasks.AddTask(task)
-			case done:
-				project.DoneTasks.AddTask(task)
-			}
+			project.Columns[column].AddTask(task)
 		}
 	}
 
@@ -521,12 +760,6 @@ func (d *Database) RefreshProjects(day int) error {
 	for _, p := range projectList {
 		projects = append(projects, p)
 	}
-	allTaskProject := &Project{
-		ProjectName: AllTasks,
-		TodoTasks:   getTodoTasks(allTasks, day),
-		DoingTasks:  getDoingTasks(allTasks, day),
-		DoneTasks:   getDoneTasks(allTasks, day),
-	}
 	projects = append(projects, allTaskProject)
 
 	// sort projects
@@ -546,13 +779,41 @@ func (d *Database) RefreshProjects(day int) error {
 		}
 	})
 
+	// views follow AllTasks in the order of the config
+	views := []*Project{}
+	for _, v := range d.Views {
+		pred := v.Predicate(date)
+		view := newProject(v.Name)
+		view.View = v
+		for i, tasks := range allTaskProject.Columns {
+			view.Columns[i] = *tasks.Filter(pred)
+		}
+		views = append(views, view)
+	}
+	projects = append(projects[:1], append(views, projects[1:]...)...)
+
 	d.Projects = projects
 
 	d.LivingTasks, d.HiddenTasks = devideTasks(uniqueTaskReferences(allTasks))
 
-	if err := d.SaveData(); err != nil {
-		return err
-	}
+	// 循環した依存関係はどのタスクも開始できなくなるため検出しておく
+	d.DependencyCycle = d.findCycle()
+
+	return nil
+}
 
+// GetProject returns the project named name, or nil if it does not exist.
+// A real project takes precedence over a view of the same name.
+func (d *Database) GetProject(name string) *Project {
+	for _, p := range d.Projects {
+		if p.ProjectName == name && p.View == nil {
+			return p
+		}
+	}
+	for _, p := range d.Projects {
+		if p.ProjectName == name {
+			return p
+		}
+	}
 	return nil
 }
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/1set/todotxt"
+	tsk "github.com/apxxxxxxe/kanban.txt/internal/task"
+)
+
+// DependencyIDs returns the ids of the tasks which t depends on
+func DependencyIDs(t *todotxt.Task) []string {
+	ids := []string{}
+	for _, id := range strings.Split(t.AdditionalTags[tsk.KeyDep], ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Blockers returns the open tasks which t depends on.
+// Unknown ids do not block t.
+func (d *Database) Blockers(t *todotxt.Task) TaskReferences {
+	blockers := TaskReferences{}
+	for _, id := range DependencyIDs(t) {
+		if dep := d.FindTask(id); dep != nil && !dep.Completed {
+			blockers = append(blockers, dep)
+		}
+	}
+	return blockers
+}
+
+// IsBlocked reports whether t has open prerequisites
+func (d *Database) IsBlocked(t *todotxt.Task) bool {
+	return len(d.Blockers(t)) > 0
+}
+
+// CheckDependencies returns an error if t depends on unknown tasks, itself or a cycle
+func (d *Database) CheckDependencies(t *todotxt.Task) error {
+	for _, id := range DependencyIDs(t) {
+		if id == t.AdditionalTags[tsk.KeyID] {
+			return fmt.Errorf("a task cannot depend on itself")
+		}
+		if d.FindTask(id) == nil {
+			return fmt.Errorf("task not found: %s", id)
+		}
+	}
+	if cycle := d.findCycle(); cycle != nil {
+		return fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+	return nil
+}
+
+// findCycle returns the ids of a dependency cycle, or nil if there is none
+func (d *Database) findCycle() []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	path := []string{}
+
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		switch state[id] {
+		case visiting:
+			// pathの中でidから始まる部分が循環
+			for i := range path {
+				if path[i] == id {
+					return append(append([]string{}, path[i:]...), id)
+				}
+			}
+		case visited:
+			return nil
+		}
+
+		t := d.FindTask(id)
+		if t == nil {
+			return nil
+		}
+		state[id] = visiting
+		path = append(path, id)
+		for _, dep := range DependencyIDs(t) {
+			if cycle := visit(dep); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = visited
+		return nil
+	}
+
+	for _, t := range d.LivingTasks {
+		if id, ok := t.AdditionalTags[tsk.KeyID]; ok && state[id] == unvisited {
+			if cycle := visit(id); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	lockTimeout  = 3 * time.Second
+	lockInterval = 50 * time.Millisecond
+	// a lock is held only while saving, so an older one was left by a crash
+	staleLockAge = time.Minute
+)
+
+var ErrLocked = errors.New("data directory is locked by another process")
+
+type lockInfo struct {
+	PID      int       `json:"pid"`
+	Hostname string    `json:"hostname"`
+	LockedAt time.Time `json:"locked_at"`
+}
+
+// lockDataDir acquires the advisory lock of the data directory
+// and returns the function to release it.
+// It waits up to lockTimeout for another process to release the lock.
+func lockDataDir() (func(), error) {
+	if err := os.MkdirAll(getDataPath(), 0755); err != nil {
+		return nil, err
+	}
+	lockPath := filepath.Join(getDataPath(), LockFile)
+
+	hostname, _ := os.Hostname()
+	info := lockInfo{PID: os.Getpid(), Hostname: hostname}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		info.LockedAt = time.Now()
+		err := createLockFile(lockPath, info)
+		if err == nil {
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		holder, err := readLockFile(lockPath)
+		if errors.Is(err, os.ErrNotExist) {
+			// released just now
+			continue
+		}
+		if err != nil || isStaleLock(holder, hostname) {
+			// a broken or stale lock is left by a crashed process
+			if err := os.Remove(lockPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: pid %d on %s since %s",
+				ErrLocked, holder.PID, holder.Hostname, holder.LockedAt.Format(time.RFC3339))
+		}
+		time.Sleep(lockInterval)
+	}
+}
+
+func createLockFile(lockPath string, info lockInfo) error {
+	fp, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(info)
+	if err == nil {
+		_, err = fp.Write(b)
+	}
+	if cerr := fp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(lockPath)
+	}
+	return err
+}
+
+func readLockFile(lockPath string) (lockInfo, error) {
+	var info lockInfo
+	b, err := os.ReadFile(lockPath)
+	if err != nil {
+		return info, err
+	}
+	if len(b) == 0 {
+		// the holder has not written its information yet
+		return lockInfo{LockedAt: time.Now()}, nil
+	}
+	err = json.Unmarshal(b, &info)
+	return info, err
+}
+
+func isStaleLock(holder lockInfo, hostname string) bool {
+	if time.Since(holder.LockedAt) > staleLockAge {
+		return true
+	}
+	if holder.Hostname == hostname && holder.PID != 0 && !processExists(holder.PID) {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package db
+
+import "os"
+
+func processExists(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
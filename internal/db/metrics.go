@@ -0,0 +1,218 @@
+package db
+
+import (
+	"sort"
+	"time"
+
+	"github.com/1set/todotxt"
+	tsk "github.com/apxxxxxxe/kanban.txt/internal/task"
+	"github.com/apxxxxxxe/kanban.txt/pkg/util"
+)
+
+// FlowMetrics is the flow of the tasks of a project.
+// The times are in days since the dates of todo.txt have no clock time.
+type FlowMetrics struct {
+	ProjectName string
+	// created to completed
+	LeadTime Percentiles
+	// started (doing:) to completed
+	CycleTime  Percentiles
+	Throughput []WeeklyThroughput
+	// the open tasks in progress, the oldest first
+	Aging []AgingTask
+}
+
+type Percentiles struct {
+	Count int
+	P50   int
+	P85   int
+	P95   int
+}
+
+type WeeklyThroughput struct {
+	// the Monday of the week
+	Week      time.Time
+	Completed int
+}
+
+type AgingTask struct {
+	Task *todotxt.Task
+	Days int
+}
+
+// FlowMetrics computes the metrics of p from the living, the hidden and the rotated tasks
+// with the throughput of the last weeks.
+func (d *Database) FlowMetrics(p *Project, now time.Time, weeks int) (*FlowMetrics, error) {
+	today := util.RemoveClockTime(now)
+	tasks, err := d.projectHistory(p, today)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &FlowMetrics{ProjectName: p.ProjectName}
+
+	// 週は月曜始まり
+	thisWeek := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	for i := weeks - 1; i >= 0; i-- {
+		m.Throughput = append(m.Throughput, WeeklyThroughput{Week: thisWeek.AddDate(0, 0, -7*i)})
+	}
+
+	leads, cycles := []int{}, []int{}
+	for _, t := range tasks {
+		if !t.Completed {
+			if d.IsWorkColumn(d.ColumnIndex(t)) {
+				start, ok := startDate(t)
+				if !ok {
+					start = t.CreatedDate
+				}
+				m.Aging = append(m.Aging, AgingTask{Task: t, Days: days(start, today)})
+			}
+			continue
+		}
+		if t.CompletedDate.IsZero() {
+			continue
+		}
+
+		if !t.CreatedDate.IsZero() {
+			leads = append(leads, days(t.CreatedDate, t.CompletedDate))
+		}
+		if start, ok := startDate(t); ok {
+			cycles = append(cycles, days(start, t.CompletedDate))
+		}
+		for i := range m.Throughput {
+			w := &m.Throughput[i]
+			if !t.CompletedDate.Before(w.Week) && t.CompletedDate.Before(w.Week.AddDate(0, 0, 7)) {
+				w.Completed++
+			}
+		}
+	}
+	m.LeadTime = percentiles(leads)
+	m.CycleTime = percentiles(cycles)
+
+	sort.SliceStable(m.Aging, func(i, j int) bool {
+		return m.Aging[i].Days > m.Aging[j].Days
+	})
+	return m, nil
+}
+
+// projectHistory returns the living, the hidden and the rotated tasks of p.
+// The tasks in done.txt are read since the metrics would shrink whenever old tasks are rotated.
+func (d *Database) projectHistory(p *Project, today time.Time) (TaskReferences, error) {
+	done, err := d.DoneTasks()
+	if err != nil {
+		return nil, err
+	}
+	tasks := append(append(append(TaskReferences{}, d.LivingTasks...), d.HiddenTasks...), done...)
+	return projectTasks(p, today, tasks), nil
+}
+
+// projectTasks returns the tasks of p among tasks
+func projectTasks(p *Project, today time.Time, tasks TaskReferences) TaskReferences {
+	result := TaskReferences{}
+	for _, t := range tasks {
+		switch {
+		case p.View != nil:
+			if !p.View.Predicate(today)(*t) {
+				continue
+			}
+		case p.ProjectName != AllTasks:
+			if !containsString(ProjectNames(t), p.ProjectName) {
+				continue
+			}
+		}
+		result = append(result, t)
+	}
+	return result
+}
+
+// FlowDay is the number of the tasks in each state at the end of a day
+type FlowDay struct {
+	Date  time.Time
+	Todo  int
+	Doing int
+	Done  int
+}
+
+// Remaining returns the number of the open tasks for the burndown
+func (f FlowDay) Remaining() int {
+	return f.Todo + f.Doing
+}
+
+// FlowHistory reconstructs the states of the tasks of p on each day from from to to
+// by the created date, the doing: tag and the completed date.
+func (d *Database) FlowHistory(p *Project, from, to time.Time) ([]FlowDay, error) {
+	from, to = util.RemoveClockTime(from), util.RemoveClockTime(to)
+	tasks, err := d.projectHistory(p, to)
+	if err != nil {
+		return nil, err
+	}
+
+	history := []FlowDay{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		f := FlowDay{Date: day}
+		for _, t := range tasks {
+			created := t.CreatedDate
+			start, started := startDate(t)
+			// 作成日のないタスクは最初に記録された日から数える
+			if created.IsZero() && started {
+				created = start
+			}
+			if created.IsZero() && t.Completed {
+				created = t.CompletedDate
+			}
+			if created.IsZero() || created.After(day) {
+				continue
+			}
+
+			switch {
+			case t.Completed && !t.CompletedDate.IsZero() && !t.CompletedDate.After(day):
+				f.Done++
+			case started && !start.After(day):
+				f.Doing++
+			default:
+				f.Todo++
+			}
+		}
+		history = append(history, f)
+	}
+	return history, nil
+}
+
+// startDate returns the date of the doing: tag of t
+func startDate(t *todotxt.Task) (time.Time, bool) {
+	v, ok := t.AdditionalTags[tsk.KeyStartDoing]
+	if !ok {
+		return time.Time{}, false
+	}
+	date, err := time.ParseInLocation(todotxt.DateLayout, v, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// days returns the number of the days from from to to, 0 if to is before from
+func days(from, to time.Time) int {
+	from, to = util.RemoveClockTime(from), util.RemoveClockTime(to)
+	if to.Before(from) {
+		return 0
+	}
+	// 夏時間で1日が24時間でない日があるため丸める
+	return int(to.Sub(from).Hours()/24 + 0.5)
+}
+
+// percentiles uses the nearest-rank method
+func percentiles(values []int) Percentiles {
+	if len(values) == 0 {
+		return Percentiles{}
+	}
+	sort.Ints(values)
+	rank := func(p int) int {
+		i := (p*len(values)+99)/100 - 1
+		if i < 0 {
+			i = 0
+		}
+		return values[i]
+	}
+	return Percentiles{Count: len(values), P50: rank(50), P85: rank(85), P95: rank(95)}
+}
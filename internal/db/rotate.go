@@ -0,0 +1,216 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/1set/todotxt"
+	tsk "github.com/apxxxxxxe/kanban.txt/internal/task"
+	"github.com/apxxxxxxe/kanban.txt/pkg/util"
+)
+
+// keepDoneTasks returns the number of the completed tasks kept in todo.txt, or -1 for no limit
+func (d *Database) keepDoneTasks() int {
+	switch {
+	case d.KeepDoneTasks == 0:
+		return KeepDoneTaskCount
+	case d.KeepDoneTasks < 0:
+		return -1
+	}
+	return d.KeepDoneTasks
+}
+
+// rotateDoneTasks moves the completed tasks beyond KeepDoneTasks or older than KeepDoneDays
+// from the living and the hidden tasks to the lines which the next save appends to done.txt.
+// The latest completed instance of each recurring series, the series limited by count:,
+// the tasks referred by open tasks and the completed subtasks of open tasks stay
+// since the recurrence, the dependencies and the progress of the parents depend on them.
+// The archived tasks are rotated as well to keep todo.txt bounded,
+// and the archive entries left with no task are removed.
+func (d *Database) rotateDoneTasks(now time.Time) {
+	allTasks := append(append(TaskReferences{}, d.LivingTasks...), d.HiddenTasks...)
+
+	// 系列ごとに最新の完了済みタスクを残す
+	latest := map[string]*todotxt.Task{}
+	// 未完了のタスクが親や先行タスクとして参照しているID
+	referred := map[string]bool{}
+	// 未完了のタスクのID、その完了済みの子タスクは進捗の計算に使う
+	openIDs := map[string]bool{}
+	for _, t := range d.LivingTasks {
+		if id, ok := t.AdditionalTags[tsk.KeyID]; ok && !t.Completed {
+			openIDs[id] = true
+		}
+	}
+	for _, t := range allTasks {
+		if !t.Completed {
+			if parent, ok := t.AdditionalTags[tsk.KeyParent]; ok {
+				referred[parent] = true
+			}
+			for _, id := range DependencyIDs(t) {
+				referred[id] = true
+			}
+			continue
+		}
+		recID, ok := t.AdditionalTags[tsk.KeyRecID]
+		if !ok {
+			continue
+		}
+		if l, ok := latest[recID]; !ok || t.CompletedDate.After(l.CompletedDate) ||
+			(t.CompletedDate.Equal(l.CompletedDate) && t.CreatedDate.After(l.CreatedDate)) {
+			latest[recID] = t
+		}
+	}
+
+	candidates := TaskReferences{}
+	for _, t := range allTasks {
+		if !t.Completed {
+			continue
+		}
+		if id, ok := t.AdditionalTags[tsk.KeyID]; ok && referred[id] {
+			continue
+		}
+		if parent, ok := t.AdditionalTags[tsk.KeyParent]; ok && openIDs[parent] {
+			continue
+		}
+		if recID, ok := t.AdditionalTags[tsk.KeyRecID]; ok {
+			// count:の系列は残っているタスクの数で終了を判定するため移動しない
+			if _, counted := t.AdditionalTags[tsk.KeyRecCount]; counted || latest[recID] == t {
+				continue
+			}
+		}
+		candidates = append(candidates, t)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].CompletedDate.After(candidates[j].CompletedDate)
+	})
+
+	keep := d.keepDoneTasks()
+	var oldest time.Time
+	if d.KeepDoneDays > 0 {
+		oldest = util.RemoveClockTime(now).AddDate(0, 0, -d.KeepDoneDays)
+	}
+
+	rotated := TaskReferences{}
+	isRotated := map[*todotxt.Task]bool{}
+	for i, t := range candidates {
+		if (keep >= 0 && i >= keep) || (!oldest.IsZero() && t.CompletedDate.Before(oldest)) {
+			rotated = append(rotated, t)
+			isRotated[t] = true
+		}
+	}
+	if len(rotated) == 0 {
+		return
+	}
+
+	// Filterは値を渡すためポインタで比較できない
+	remove := func(tasks TaskReferences) TaskReferences {
+		rest := TaskReferences{}
+		for _, t := range tasks {
+			if !isRotated[t] {
+				rest = append(rest, t)
+			}
+		}
+		return rest
+	}
+	d.LivingTasks = remove(d.LivingTasks)
+	d.HiddenTasks = remove(d.HiddenTasks)
+	for _, t := range rotated {
+		// 系列の他のタスクが残っていればアーカイブしたままにする
+		if key, ok := archiveKey(t); ok && d.IsArchived(t) && len(d.archivedTasks(key)) == 0 {
+			d.Unarchive(key)
+		}
+	}
+	for _, line := range taskLines(rotated) {
+		// undoで戻した行はdone.txtに残っているので追記しない
+		if d.unrotatedLines[line] > 0 {
+			d.unrotatedLines[line]--
+			d.rotatedLines[line]++
+			continue
+		}
+		d.doneLines = append(d.doneLines, line)
+	}
+}
+
+// unrotate records the lines which s brings back into todo.txt from done.txt.
+// It is called before s is restored.
+func (d *Database) unrotate(s Snapshot) {
+	current := toCounts(d.currentLines())
+	restoring := toCounts(s.lines)
+	for line, n := range restoring {
+		restoring[line] = n - current[line]
+	}
+
+	// 保存前の行はdone.txtに書かずに戻す
+	pending := []string{}
+	for _, line := range d.doneLines {
+		if restoring[line] > 0 {
+			restoring[line]--
+			continue
+		}
+		pending = append(pending, line)
+	}
+	d.doneLines = pending
+
+	for line, restored := range restoring {
+		if restored > d.rotatedLines[line] {
+			restored = d.rotatedLines[line]
+		}
+		if restored <= 0 {
+			continue
+		}
+		if d.unrotatedLines == nil {
+			d.unrotatedLines = map[string]int{}
+		}
+		d.rotatedLines[line] -= restored
+		d.unrotatedLines[line] += restored
+	}
+}
+
+// doneFileContent returns done.txt with the rotated lines appended
+func (d *Database) doneFileContent() ([]byte, error) {
+	b, err := os.ReadFile(filepath.Join(getDataPath(), DoneFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	content := string(b)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	for _, line := range d.doneLines {
+		content += line + "\n"
+	}
+	return []byte(content), nil
+}
+
+// DoneTasks returns the tasks rotated into done.txt, including the ones not saved yet.
+// The lines restored into todo.txt by an undo are left out not to be counted twice.
+func (d *Database) DoneTasks() (TaskReferences, error) {
+	b, err := os.ReadFile(filepath.Join(getDataPath(), DoneFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	// 同じ行が複数あり得るので、undoで戻した数だけ除く
+	restored := map[string]int{}
+	for line, n := range d.unrotatedLines {
+		restored[line] = n
+	}
+	lines := []string{}
+	for _, line := range append(strings.Split(string(b), "\n"), d.doneLines...) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if restored[line] > 0 {
+			restored[line]--
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return parseLines(lines)
+}
@@ -0,0 +1,33 @@
+package db
+
+// Snapshot is the state of all tasks at a moment, used to undo changes.
+type Snapshot struct {
+	lines   []string
+	archive []string
+}
+
+// Snapshot returns the current state of the tasks.
+func (d *Database) Snapshot() Snapshot {
+	return Snapshot{
+		lines:   d.currentLines(),
+		archive: append([]string{}, d.ArchivedTasks...),
+	}
+}
+
+// Restore replaces the tasks with the ones in s.
+// The restored tasks are saved by the next RefreshProjects.
+func (d *Database) Restore(s Snapshot) error {
+	tasks, err := parseLines(s.lines)
+	if err != nil {
+		return err
+	}
+	d.unrotate(s)
+	d.LivingTasks, d.HiddenTasks = devideTasks(tasks)
+	d.ArchivedTasks = append([]string{}, s.archive...)
+	return nil
+}
+
+// Equal reports whether s and o have the same tasks.
+func (s Snapshot) Equal(o Snapshot) bool {
+	return equalStrings(s.lines, o.lines) && equalStrings(s.archive, o.archive)
+}
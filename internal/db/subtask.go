@@ -0,0 +1,135 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/1set/todotxt"
+	tsk "github.com/apxxxxxxe/kanban.txt/internal/task"
+	"github.com/google/uuid"
+)
+
+// the length of the ids given by EnsureID
+const idLength = 8
+
+// EnsureID returns the id of t, giving it a new one if it has none.
+func (d *Database) EnsureID(t *todotxt.Task) string {
+	if id, ok := t.AdditionalTags[tsk.KeyID]; ok && id != "" {
+		return id
+	}
+
+	var id string
+	for {
+		id = strings.ReplaceAll(uuid.New().String(), "-", "")[:idLength]
+		if d.FindTask(id) == nil {
+			break
+		}
+	}
+	if t.AdditionalTags == nil {
+		t.AdditionalTags = map[string]string{}
+	}
+	t.AdditionalTags[tsk.KeyID] = id
+	return id
+}
+
+// FindTask returns the living or hidden task with the id, or nil if it does not exist.
+// The id of a recurring task resolves to the current task of its series
+// since the id is not copied to the new occurrences.
+func (d *Database) FindTask(id string) *todotxt.Task {
+	if id == "" {
+		return nil
+	}
+	for _, t := range append(append(TaskReferences{}, d.LivingTasks...), d.HiddenTasks...) {
+		if t.AdditionalTags[tsk.KeyID] != id {
+			continue
+		}
+		if recID, ok := t.AdditionalTags[tsk.KeyRecID]; ok {
+			return d.currentOccurrence(recID)
+		}
+		return t
+	}
+	return nil
+}
+
+// currentOccurrence returns the newest open task of the series of recID,
+// or the newest one if all of them are completed
+func (d *Database) currentOccurrence(recID string) *todotxt.Task {
+	var current *todotxt.Task
+	for _, t := range append(append(TaskReferences{}, d.LivingTasks...), d.HiddenTasks...) {
+		if t.AdditionalTags[tsk.KeyRecID] != recID {
+			continue
+		}
+		switch {
+		case current == nil,
+			current.Completed && !t.Completed,
+			current.Completed == t.Completed && t.CreatedDate.After(current.CreatedDate):
+			current = t
+		}
+	}
+	return current
+}
+
+// Children returns the subtasks of t.
+func (d *Database) Children(t *todotxt.Task) TaskReferences {
+	children := TaskReferences{}
+	id, ok := t.AdditionalTags[tsk.KeyID]
+	if !ok {
+		return children
+	}
+	for _, c := range d.LivingTasks {
+		if c != t && c.AdditionalTags[tsk.KeyParent] == id {
+			children = append(children, c)
+		}
+	}
+	return children
+}
+
+// Progress returns the number of the completed subtasks of t and of all of them.
+func (d *Database) Progress(t *todotxt.Task) (int, int) {
+	children := d.Children(t)
+	done := 0
+	for _, c := range children {
+		if c.Completed {
+			done++
+		}
+	}
+	return done, len(children)
+}
+
+// AddSubtask adds t as a subtask of parent.
+// t is put in the projects of parent unless it has its own.
+func (d *Database) AddSubtask(parent, t *todotxt.Task) {
+	if len(ProjectNames(t)) == 1 && ProjectNames(t)[0] == NoProject {
+		t.Projects = append([]string{}, parent.Projects...)
+	}
+	if t.AdditionalTags == nil {
+		t.AdditionalTags = map[string]string{}
+	}
+	t.AdditionalTags[tsk.KeyParent] = d.EnsureID(parent)
+	d.AddTask(t)
+}
+
+// CheckIDChange returns an error if t cannot take the id,
+// which is taken by another task, or if the current id of t is referred by other tasks through parent: or dep:
+func (d *Database) CheckIDChange(t *todotxt.Task, id string) error {
+	oldID := t.AdditionalTags[tsk.KeyID]
+	if id == oldID {
+		return nil
+	}
+	for _, other := range append(append(TaskReferences{}, d.LivingTasks...), d.HiddenTasks...) {
+		if other == t {
+			continue
+		}
+		if id != "" && other.AdditionalTags[tsk.KeyID] == id {
+			return fmt.Errorf("id already in use: %s", id)
+		}
+		if oldID == "" {
+			continue
+		}
+		// 参照が切れるので、参照しているタスクがあるうちはIDを変えさせない
+		if other.AdditionalTags[tsk.KeyParent] == oldID || containsString(DependencyIDs(other), oldID) {
+			return fmt.Errorf("id %s is referred by: %s", oldID, other.Todo)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/1set/todotxt"
+	tsk "github.com/apxxxxxxe/kanban.txt/internal/task"
+)
+
+var ErrConflict = errors.New("todo.txt was changed by another program while editing the same task")
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+func (d *Database) markSaved(lines []string, archive []string) {
+	d.savedLines = lines
+	d.savedArchive = append([]string{}, archive...)
+	d.todoStamp = statFile(filepath.Join(getDataPath(), ImportFile))
+	d.archiveStamp = statFile(filepath.Join(getDataPath(), ArchiveFile))
+}
+
+// IsModifiedOnDisk reports whether todo.txt or archive.json was changed
+// by another program after the last LoadData or SaveData.
+func (d *Database) IsModifiedOnDisk() bool {
+	todo := statFile(filepath.Join(getDataPath(), ImportFile))
+	archive := statFile(filepath.Join(getDataPath(), ArchiveFile))
+	return !todo.modTime.Equal(d.todoStamp.modTime) || todo.size != d.todoStamp.size ||
+		!archive.modTime.Equal(d.archiveStamp.modTime) || archive.size != d.archiveStamp.size
+}
+
+// HasUnsavedChanges reports whether the tasks in memory differ from the last saved ones.
+func (d *Database) HasUnsavedChanges() bool {
+	return !equalStrings(d.currentLines(), d.savedLines) || !equalStrings(d.ArchivedTasks, d.savedArchive)
+}
+
+// SyncWithDisk merges the changes made to the data files by another program
+// into the tasks in memory.
+// It returns ErrConflict without changing anything
+// when the same task was edited both in memory and on disk.
+func (d *Database) SyncWithDisk() error {
+	if !d.IsModifiedOnDisk() {
+		return nil
+	}
+
+	disk := &Database{}
+	if err := disk.LoadData(); err != nil {
+		return err
+	}
+
+	lines, err := mergeLines(d.savedLines, d.currentLines(), disk.savedLines)
+	if err != nil {
+		return err
+	}
+
+	tasks, err := parseLines(lines)
+	if err != nil {
+		return err
+	}
+
+	d.LivingTasks, d.HiddenTasks = devideTasks(tasks)
+	d.ArchivedTasks = mergeSets(d.savedArchive, d.ArchivedTasks, disk.ArchivedTasks)
+	d.savedLines = disk.savedLines
+	d.savedArchive = disk.savedArchive
+	d.todoStamp = disk.todoStamp
+	d.archiveStamp = disk.archiveStamp
+	return nil
+}
+
+// ReloadFromDisk discards the tasks in memory and loads the data files again.
+func (d *Database) ReloadFromDisk() error {
+	return d.LoadData()
+}
+
+func (d *Database) currentLines() []string {
+	allTasks := TaskReferences{}
+	allTasks = append(allTasks, d.LivingTasks...)
+	allTasks = append(allTasks, d.HiddenTasks...)
+	return taskLines(allTasks)
+}
+
+// parseLines parses the lines of todo.txt in the same way as LoadData
+func parseLines(lines []string) (TaskReferences, error) {
+	tmpList := todotxt.NewTaskList()
+	for _, line := range lines {
+		t, err := todotxt.ParseTask(line)
+		if err != nil {
+			return nil, err
+		}
+		tmpList.AddTask(t)
+	}
+	return toTaskReferences(tmpList), nil
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeSets applies the difference between base and ours to theirs.
+// The lines are counted as a multiset since identical lines are legitimate in todo.txt.
+func mergeSets(base, ours, theirs []string) []string {
+	return mergeCounts(base, ours, theirs, nil)
+}
+
+// mergeCounts is mergeSets in which the lines of shared,
+// added by ours and theirs as the same change, are added only once.
+func mergeCounts(base, ours, theirs []string, shared map[string]bool) []string {
+	baseCount, oursCount, theirsCount := toCounts(base), toCounts(ours), toCounts(theirs)
+
+	limit := func(s string) int {
+		if shared[s] {
+			return baseCount[s] + maxInt(oursCount[s]-baseCount[s], theirsCount[s]-baseCount[s])
+		}
+		return theirsCount[s] + oursCount[s] - baseCount[s]
+	}
+
+	merged := []string{}
+	mergedCount := map[string]int{}
+	// theirsの順序を保ち、oursで追加された行を後ろに加える
+	for _, s := range append(append([]string{}, theirs...), ours...) {
+		if mergedCount[s] < limit(s) {
+			merged = append(merged, s)
+			mergedCount[s]++
+		}
+	}
+	return merged
+}
+
+// mergeLines merges the lines of todo.txt in the same way as mergeSets.
+// A line of base which both ours and theirs replaced or removed is a conflict
+// unless they made the same change to it.
+// The replacement of a line is found by the identity of the task, see taskKey.
+// A side which removed a single line without a replacement of the same identity
+// is taken to have renamed it to the lines it added with no identity of the removed lines,
+// so that renaming a task on one side and editing it on the other is a conflict.
+func mergeLines(base, ours, theirs []string) ([]string, error) {
+	addedOurs, removedOurs := diffLines(base, ours)
+	addedTheirs, removedTheirs := diffLines(base, theirs)
+
+	removedKeys := map[string]bool{}
+	for _, line := range append(append([]string{}, removedOurs...), removedTheirs...) {
+		removedKeys[taskKey(line)] = true
+	}
+	oursChanges := newLineChanges(addedOurs, removedOurs, removedKeys)
+	theirsChanges := newLineChanges(addedTheirs, removedTheirs, removedKeys)
+
+	oursRemoved, theirsRemoved := toCounts(removedOurs), toCounts(removedTheirs)
+	shared := map[string]bool{}
+	for line := range toCounts(base) {
+		if oursRemoved[line] == 0 || theirsRemoved[line] == 0 {
+			continue
+		}
+		o, th := oursChanges.replacement(line), theirsChanges.replacement(line)
+		if !equalStrings(o, th) {
+			return nil, ErrConflict
+		}
+		// 同じ変更は一つにまとめる
+		for _, l := range o {
+			shared[l] = true
+		}
+	}
+
+	return mergeCounts(base, ours, theirs, shared), nil
+}
+
+// lineChanges is the lines added by one side of mergeLines grouped by the identity of the task
+type lineChanges struct {
+	byKey map[string][]string
+	// the added lines with no identity of the removed lines
+	unidentified []string
+	// the removed lines which have no replacement of the same identity
+	unmatched int
+}
+
+func newLineChanges(added, removed []string, removedKeys map[string]bool) *lineChanges {
+	c := &lineChanges{byKey: map[string][]string{}, unidentified: []string{}}
+	for _, line := range added {
+		key := taskKey(line)
+		if removedKeys[key] {
+			c.byKey[key] = append(c.byKey[key], line)
+		} else {
+			c.unidentified = append(c.unidentified, line)
+		}
+	}
+	for _, line := range removed {
+		if len(c.byKey[taskKey(line)]) == 0 {
+			c.unmatched++
+		}
+	}
+	return c
+}
+
+// replacement returns the sorted lines which replaced the removed line, none if it was deleted
+func (c *lineChanges) replacement(line string) []string {
+	lines := c.byKey[taskKey(line)]
+	if len(lines) == 0 && c.unmatched == 1 {
+		// 名前を変えたタスクは同一性で対応付けられないので、残った追加行を置き換えとみなす
+		lines = c.unidentified
+	}
+	lines = append([]string{}, lines...)
+	sort.Strings(lines)
+	return lines
+}
+
+// diffLines returns the lines added to and removed from base in lines, counted as a multiset
+func diffLines(base, lines []string) ([]string, []string) {
+	baseCount, count := toCounts(base), toCounts(lines)
+	added, removed := []string{}, []string{}
+	seen := map[string]int{}
+	for _, line := range lines {
+		seen[line]++
+		if seen[line] > baseCount[line] {
+			added = append(added, line)
+		}
+	}
+	seen = map[string]int{}
+	for _, line := range base {
+		seen[line]++
+		if seen[line] > count[line] {
+			removed = append(removed, line)
+		}
+	}
+	return added, removed
+}
+
+// taskKey returns the identity of the task of line which survives editing it:
+// its id, its recurring series and created date, or its text
+func taskKey(line string) string {
+	t, err := todotxt.ParseTask(line)
+	if err != nil {
+		return "line:" + line
+	}
+	if id, ok := t.AdditionalTags[tsk.KeyID]; ok && id != "" {
+		return "id:" + id
+	}
+	if recID, ok := t.AdditionalTags[tsk.KeyRecID]; ok {
+		return "recid:" + recID + " " + t.CreatedDate.Format(todotxt.DateLayout)
+	}
+	return "todo:" + t.Todo
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func toCounts(a []string) map[string]int {
+	m := map[string]int{}
+	for _, s := range a {
+		m[s]++
+	}
+	return m
+}
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func sortedLines(lines []string) []string {
+	sorted := append([]string{}, lines...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func TestMergeSets(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs []string
+		want               []string
+	}{
+		{"unchanged", []string{"a", "b"}, []string{"a", "b"}, []string{"a", "b"}, []string{"a", "b"}},
+		{"added on both sides", []string{"a"}, []string{"a", "b"}, []string{"a", "c"}, []string{"a", "c", "b"}},
+		{"removed in ours", []string{"a", "b"}, []string{"a"}, []string{"a", "b"}, []string{"a"}},
+		{"removed in both", []string{"a", "b"}, []string{"a"}, []string{"a"}, []string{"a"}},
+		{"duplicate added in ours", []string{"a"}, []string{"a", "a"}, []string{"a", "b"}, []string{"a", "b", "a"}},
+		{"one of duplicates removed", []string{"a", "a", "b"}, []string{"a", "b"}, []string{"a", "a", "b", "c"}, []string{"a", "b", "c"}},
+		{"duplicates added on both sides", []string{}, []string{"a"}, []string{"a"}, []string{"a", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeSets(tt.base, tt.ours, tt.theirs)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeLines(t *testing.T) {
+	const (
+		a = "2026-10-18 a +p"
+		b = "2026-10-18 b +p"
+	)
+	tests := []struct {
+		name               string
+		base, ours, theirs []string
+		// nil for a conflict
+		want []string
+	}{
+		{
+			name: "unchanged",
+			base: []string{a, b}, ours: []string{a, b}, theirs: []string{a, b},
+			want: []string{a, b},
+		},
+		{
+			name: "added on both sides",
+			base: []string{a}, ours: []string{a, b}, theirs: []string{a, "2026-10-18 c +p"},
+			want: []string{a, b, "2026-10-18 c +p"},
+		},
+		{
+			name: "different tasks edited",
+			base: []string{a, b},
+			ours: []string{"x 2026-10-18 2026-10-18 a +p", b}, theirs: []string{a, "2026-10-18 b +p due:2026-10-20"},
+			want: []string{"x 2026-10-18 2026-10-18 a +p", "2026-10-18 b +p due:2026-10-20"},
+		},
+		{
+			name: "same task edited differently",
+			base: []string{a, b},
+			ours: []string{"x 2026-10-18 2026-10-18 a +p", b}, theirs: []string{"2026-10-18 a +p due:2026-10-20", b},
+		},
+		{
+			name: "renamed in ours and edited in theirs",
+			base: []string{a, b},
+			ours: []string{"2026-10-18 renamed +p", b}, theirs: []string{"x 2026-10-18 2026-10-18 a +p", b},
+		},
+		{
+			name: "renamed differently",
+			base: []string{a, b},
+			ours: []string{"2026-10-18 one +p", b}, theirs: []string{"2026-10-18 two +p", b},
+		},
+		{
+			name: "renamed in ours while another task is deleted on both sides",
+			base: []string{a, b},
+			ours: []string{"2026-10-18 renamed +p"}, theirs: []string{a},
+			want: []string{"2026-10-18 renamed +p"},
+		},
+		{
+			name: "same edit on both sides",
+			base: []string{a, b},
+			ours: []string{"x 2026-10-18 2026-10-18 a +p", b}, theirs: []string{"x 2026-10-18 2026-10-18 a +p", b},
+			want: []string{"x 2026-10-18 2026-10-18 a +p", b},
+		},
+		{
+			name: "deleted in ours and edited in theirs",
+			base: []string{a, b},
+			ours: []string{b}, theirs: []string{"x 2026-10-18 2026-10-18 a +p", b},
+		},
+		{
+			name: "deleted on both sides",
+			base: []string{a, b}, ours: []string{b}, theirs: []string{b},
+			want: []string{b},
+		},
+		{
+			name: "deleted in ours and another task edited in theirs",
+			base: []string{a, b},
+			ours: []string{b}, theirs: []string{a, "x 2026-10-18 2026-10-18 b +p"},
+			want: []string{"x 2026-10-18 2026-10-18 b +p"},
+		},
+		{
+			name: "renamed by id and edited in theirs",
+			base: []string{"2026-10-18 a +p id:1", b},
+			ours: []string{"2026-10-18 renamed +p id:1", b}, theirs: []string{"2026-10-18 a +p id:1 due:2026-10-20", b},
+		},
+		{
+			name:   "tasks with ids renamed on both sides",
+			base:   []string{"2026-10-18 a +p id:1", "2026-10-18 b +p id:2"},
+			ours:   []string{"2026-10-18 one +p id:1", "2026-10-18 b +p id:2"},
+			theirs: []string{"2026-10-18 a +p id:1", "2026-10-18 two +p id:2"},
+			want:   []string{"2026-10-18 one +p id:1", "2026-10-18 two +p id:2"},
+		},
+		{
+			name:   "occurrences of a series completed on each side",
+			base:   []string{"2026-10-17 a rec:1d recid:r", "2026-10-18 a rec:1d recid:r"},
+			ours:   []string{"x 2026-10-18 2026-10-17 a rec:1d recid:r", "2026-10-18 a rec:1d recid:r"},
+			theirs: []string{"2026-10-17 a rec:1d recid:r", "x 2026-10-18 2026-10-18 a rec:1d recid:r"},
+			want:   []string{"x 2026-10-18 2026-10-17 a rec:1d recid:r", "x 2026-10-18 2026-10-18 a rec:1d recid:r"},
+		},
+		{
+			name: "one of duplicate lines completed in ours",
+			base: []string{a, a}, ours: []string{a, "x 2026-10-18 2026-10-18 a +p"}, theirs: []string{a, a, b},
+			want: []string{a, "x 2026-10-18 2026-10-18 a +p", b},
+		},
+		{
+			name: "duplicate line added in theirs",
+			base: []string{a}, ours: []string{a, b}, theirs: []string{a, a},
+			want: []string{a, a, b},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeLines(tt.base, tt.ours, tt.theirs)
+			if tt.want == nil {
+				if !errors.Is(err, ErrConflict) {
+					t.Fatalf("got %q, %v, want a conflict", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(sortedLines(got), sortedLines(tt.want)) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package db
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/1set/todotxt"
+	tsk "github.com/apxxxxxxe/kanban.txt/internal/task"
+	"github.com/apxxxxxxe/kanban.txt/pkg/util"
+)
+
+// View is a virtual project which shows the tasks matching a query.
+//
+// The query is a list of terms separated by spaces, and every term must match.
+// Terms joined by "or" are alternatives:
+//
+//	+project        in the project
+//	@context        has the context
+//	pri<=B          has the priority A or B (also =, <, >, >=)
+//	due<=3          due within 3 days from the viewed day (also =, <, >, >=)
+//	has:rec         has the tag, or a due date or a priority with has:due and has:pri
+//	word            the text contains the word (case-insensitive)
+//	-term           does not match the term
+type View struct {
+	Name   string
+	Query  string
+	groups [][]viewTerm
+}
+
+// a viewTerm returns whether the task matches on the date
+type viewTerm func(t todotxt.Task, date time.Time) bool
+
+var viewOperators = []string{"<=", ">=", "=", "<", ">"}
+
+// ParseViews parses the views of the config
+func ParseViews(configs []*ViewConfig) ([]*View, error) {
+	views := []*View{}
+	names := map[string]bool{}
+	for _, c := range configs {
+		if c.Name == "" || c.Name == AllTasks || c.Name == NoProject {
+			return nil, fmt.Errorf("invalid view name: %q", c.Name)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("duplicate view name: %s", c.Name)
+		}
+		names[c.Name] = true
+
+		v, err := NewView(c.Name, c.Query)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, v)
+	}
+	return views, nil
+}
+
+func NewView(name, query string) (*View, error) {
+	v := &View{Name: name, Query: query}
+	group := []viewTerm{}
+	for _, word := range strings.Fields(query) {
+		if strings.EqualFold(word, "or") {
+			if len(group) == 0 {
+				return nil, fmt.Errorf("view %s: misplaced \"or\"", name)
+			}
+			v.groups = append(v.groups, group)
+			group = []viewTerm{}
+			continue
+		}
+		term, err := parseViewTerm(word)
+		if err != nil {
+			return nil, fmt.Errorf("view %s: %w", name, err)
+		}
+		group = append(group, term)
+	}
+	if len(group) == 0 {
+		return nil, fmt.Errorf("view %s: empty query", name)
+	}
+	v.groups = append(v.groups, group)
+	return v, nil
+}
+
+// Predicate returns the predicate of the view for the day viewed on date
+func (v *View) Predicate(date time.Time) todotxt.Predicate {
+	date = util.RemoveClockTime(date)
+	return func(t todotxt.Task) bool {
+		for _, group := range v.groups {
+			ok := true
+			for _, term := range group {
+				if !term(t, date) {
+					ok = false
+					break
+				}
+			}
+			if ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func parseViewTerm(word string) (viewTerm, error) {
+	if len(word) > 1 && (word[0] == '-' || word[0] == '!') {
+		term, err := parseViewTerm(word[1:])
+		if err != nil {
+			return nil, err
+		}
+		return func(t todotxt.Task, date time.Time) bool {
+			return !term(t, date)
+		}, nil
+	}
+
+	switch {
+	case len(word) > 1 && word[0] == '+':
+		return func(t todotxt.Task, date time.Time) bool {
+			return containsString(t.Projects, word[1:])
+		}, nil
+	case len(word) > 1 && word[0] == '@':
+		return func(t todotxt.Task, date time.Time) bool {
+			return containsString(t.Contexts, word[1:])
+		}, nil
+	case strings.HasPrefix(word, "has:"):
+		return filterHasField(word[len("has:"):]), nil
+	case strings.HasPrefix(word, "pri"):
+		if op, value, ok := splitOperator(word[len("pri"):]); ok {
+			return filterPriority(op, value)
+		}
+	case strings.HasPrefix(word, "due"):
+		if op, value, ok := splitOperator(word[len("due"):]); ok {
+			return filterDueDays(op, value)
+		}
+	}
+
+	word = strings.ToLower(word)
+	return func(t todotxt.Task, date time.Time) bool {
+		return strings.Contains(strings.ToLower(t.Todo), word)
+	}, nil
+}
+
+func splitOperator(s string) (string, string, bool) {
+	for _, op := range viewOperators {
+		if strings.HasPrefix(s, op) {
+			return op, s[len(op):], true
+		}
+	}
+	return "", "", false
+}
+
+func compare(op string, a, b int) bool {
+	switch op {
+	case "<=":
+		return a <= b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case ">":
+		return a > b
+	}
+	return a == b
+}
+
+func filterHasField(key string) viewTerm {
+	return func(t todotxt.Task, date time.Time) bool {
+		switch key {
+		case tsk.KeyDue:
+			return t.HasDueDate()
+		case "pri":
+			return t.HasPriority()
+		}
+		_, ok := t.AdditionalTags[key]
+		return ok
+	}
+}
+
+// filterPriority compares the priorities in alphabetical order, so pri<=B matches A and B
+func filterPriority(op, value string) (viewTerm, error) {
+	value = strings.ToUpper(value)
+	if len(value) != 1 || value[0] < 'A' || value[0] > 'Z' {
+		return nil, fmt.Errorf("invalid priority: %s", value)
+	}
+	return func(t todotxt.Task, date time.Time) bool {
+		return t.HasPriority() && compare(op, int(t.Priority[0]), int(value[0]))
+	}, nil
+}
+
+// filterDueDays compares the days until the due date
+func filterDueDays(op, value string) (viewTerm, error) {
+	days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid days: %s", value)
+	}
+	return func(t todotxt.Task, date time.Time) bool {
+		if !t.HasDueDate() {
+			return false
+		}
+		due := timeToDate(&t.DueDate)
+		left := int(math.Round(due.Sub(date).Hours() / 24))
+		return compare(op, left, days)
+	}, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
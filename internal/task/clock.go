@@ -0,0 +1,136 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/1set/todotxt"
+)
+
+// ClockLayout is the ISO 8601 basic format of the clock: tag,
+// which has no colon so that todo.txt readers do not split it
+const ClockLayout = "20060102T1504"
+
+// Stint is a period of working on a task
+type Stint struct {
+	Start time.Time
+	// zero while the clock is running
+	End time.Time
+}
+
+// Duration returns the length of the stint, counting a running one until now
+func (s Stint) Duration(now time.Time) time.Duration {
+	end := s.End
+	if end.IsZero() {
+		end = now
+	}
+	if end.Before(s.Start) {
+		return 0
+	}
+	return end.Sub(s.Start)
+}
+
+// Stints parses the clock: tag of task, which is a comma separated list of start/end intervals
+// like "20261018T0930/20261018T1045,20261019T1300/".
+func Stints(task *todotxt.Task) ([]Stint, error) {
+	v, ok := task.AdditionalTags[KeyClock]
+	if !ok || v == "" {
+		return nil, nil
+	}
+
+	stints := []Stint{}
+	for _, s := range strings.Split(v, ",") {
+		start, end, ok := strings.Cut(s, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid clock: %s", s)
+		}
+		stint := Stint{}
+		var err error
+		if stint.Start, err = time.ParseInLocation(ClockLayout, start, time.Local); err != nil {
+			return nil, fmt.Errorf("invalid clock: %s", s)
+		}
+		if end != "" {
+			if stint.End, err = time.ParseInLocation(ClockLayout, end, time.Local); err != nil {
+				return nil, fmt.Errorf("invalid clock: %s", s)
+			}
+		}
+		stints = append(stints, stint)
+	}
+	return stints, nil
+}
+
+func setStints(task *todotxt.Task, stints []Stint) {
+	if len(stints) == 0 {
+		delete(task.AdditionalTags, KeyClock)
+		return
+	}
+	values := []string{}
+	for _, s := range stints {
+		v := s.Start.Format(ClockLayout) + "/"
+		if !s.End.IsZero() {
+			v += s.End.Format(ClockLayout)
+		}
+		values = append(values, v)
+	}
+	if task.AdditionalTags == nil {
+		task.AdditionalTags = map[string]string{}
+	}
+	task.AdditionalTags[KeyClock] = strings.Join(values, ",")
+}
+
+// ClockTime returns the time of a transition made on date, the day of date at the clock time of now,
+// so that a move on another day than today is recorded on that day.
+func ClockTime(date, now time.Time) time.Time {
+	y, m, d := date.Date()
+	return time.Date(y, m, d, now.Hour(), now.Minute(), now.Second(), 0, now.Location())
+}
+
+// IsClockRunning reports whether the last stint of task is not stopped
+func IsClockRunning(task *todotxt.Task) bool {
+	stints, err := Stints(task)
+	return err == nil && len(stints) > 0 && stints[len(stints)-1].End.IsZero()
+}
+
+// ClockIn starts a new stint unless the clock is running.
+// An invalid clock: tag is left as it is.
+func ClockIn(task *todotxt.Task, now time.Time) {
+	stints, err := Stints(task)
+	if err != nil || IsClockRunning(task) {
+		return
+	}
+	setStints(task, append(stints, Stint{Start: now}))
+}
+
+// ClockOut stops the running stint
+func ClockOut(task *todotxt.Task, now time.Time) {
+	if !IsClockRunning(task) {
+		return
+	}
+	stints, _ := Stints(task)
+	last := &stints[len(stints)-1]
+	last.End = now
+	if last.End.Before(last.Start) {
+		last.End = last.Start
+	}
+	setStints(task, stints)
+}
+
+// Elapsed returns the total time spent on task
+func Elapsed(task *todotxt.Task, now time.Time) time.Duration {
+	stints, _ := Stints(task)
+	var total time.Duration
+	for _, s := range stints {
+		total += s.Duration(now)
+	}
+	return total
+}
+
+// FormatDuration formats d in minutes like "1h05m" or "20m"
+func FormatDuration(d time.Duration) string {
+	minutes := int(d.Minutes())
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh%02dm", minutes/60, minutes%60)
+}
@@ -0,0 +1,61 @@
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/1set/todotxt"
+)
+
+// noteEscapes are the characters escaped in note: since a whitespace ends the tag
+var noteEscapes = "% \t\n\r"
+
+// EncodeNote escapes the whitespaces and "%" of note as %XX to be written in a note: tag
+func EncodeNote(note string) string {
+	var sb strings.Builder
+	for i := 0; i < len(note); i++ {
+		if strings.IndexByte(noteEscapes, note[i]) >= 0 {
+			fmt.Fprintf(&sb, "%%%02X", note[i])
+		} else {
+			sb.WriteByte(note[i])
+		}
+	}
+	return sb.String()
+}
+
+// DecodeNote reverses EncodeNote.
+// A "%" not followed by an escaped character is kept as it is for the notes written by hand.
+func DecodeNote(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			if b, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil && strings.IndexByte(noteEscapes, byte(b)) >= 0 {
+				sb.WriteByte(byte(b))
+				i += 2
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// Note returns the decoded note of t
+func Note(t *todotxt.Task) string {
+	return DecodeNote(t.AdditionalTags[KeyNote])
+}
+
+// SetNote encodes note into t, removing the tag if note is empty.
+// The trailing newlines, which editors append, are dropped.
+func SetNote(t *todotxt.Task, note string) {
+	note = strings.TrimRight(strings.ReplaceAll(note, "\r\n", "\n"), "\n")
+	if strings.TrimSpace(note) == "" {
+		delete(t.AdditionalTags, KeyNote)
+		return
+	}
+	if t.AdditionalTags == nil {
+		t.AdditionalTags = map[string]string{}
+	}
+	t.AdditionalTags[KeyNote] = EncodeNote(note)
+}
@@ -0,0 +1,365 @@
+package task
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/1set/todotxt"
+)
+
+// maxSearchDays bounds the search of the next date of a weekday based rule
+const maxSearchDays = 366 * 4
+
+var ErrInvalidRecurrence = errors.New("invalid recurrence")
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Recurrence is a parsed value of the rec tag.
+//
+//	rec:3d, rec:2w, rec:1m, rec:1y  every N days, weeks, months or years
+//	rec:weekday                     every business day
+//	rec:mon,thu                     every listed day of the week
+//	rec:2tue, rec:lastfri           the Nth or the last day of the week in a month
+//	rec:lastday, rec:lastbday       the last day or the last business day of a month
+//
+// A trailing "*" repeats the task from its completion date instead of its created date.
+type Recurrence struct {
+	FromCompletion bool
+
+	// every Interval Period
+	Interval int
+	Period   string
+
+	// any of Weekdays
+	Weekdays []time.Weekday
+
+	// the Nth Weekday of a month; -1 means the last
+	Nth     int
+	Weekday time.Weekday
+
+	LastDay         bool
+	LastBusinessDay bool
+}
+
+func ParseRecurrenceRule(v string) (*Recurrence, error) {
+	invalid := func() (*Recurrence, error) {
+		return nil, errors.New(ErrInvalidRecurrence.Error() + ": " + v)
+	}
+
+	r := &Recurrence{}
+	rule := strings.ToLower(v)
+	if strings.HasSuffix(rule, "*") {
+		r.FromCompletion = true
+		rule = rule[:len(rule)-1]
+	}
+	if len(rule) < 2 {
+		return invalid()
+	}
+
+	switch rule {
+	case "weekday", "weekdays", "bday":
+		r.Weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+		return r, nil
+	case "lastday":
+		r.LastDay = true
+		return r, nil
+	case "lastbday":
+		r.LastBusinessDay = true
+		return r, nil
+	}
+
+	// every N days, weeks, months or years
+	if num, err := strconv.Atoi(rule[:len(rule)-1]); err == nil {
+		period := rule[len(rule)-1:]
+		if num <= 0 || !strings.Contains("dwmy", period) {
+			return invalid()
+		}
+		r.Interval = num
+		r.Period = period
+		return r, nil
+	}
+
+	// the Nth day of the week in a month
+	if len(rule) > 3 && !strings.Contains(rule, ",") {
+		if wd, ok := weekdayNames[rule[len(rule)-3:]]; ok {
+			nth := rule[:len(rule)-3]
+			if nth == "last" {
+				r.Nth = -1
+			} else if num, err := strconv.Atoi(nth); err == nil && num >= 1 && num <= 5 {
+				r.Nth = num
+			} else {
+				return invalid()
+			}
+			r.Weekday = wd
+			return r, nil
+		}
+	}
+
+	// any of the listed days of the week
+	for _, name := range strings.Split(rule, ",") {
+		wd, ok := weekdayNames[name]
+		if !ok {
+			return invalid()
+		}
+		r.Weekdays = append(r.Weekdays, wd)
+	}
+	return r, nil
+}
+
+// Next returns the next date of the recurrence after from.
+func (r *Recurrence) Next(from time.Time) (time.Time, error) {
+	switch r.Period {
+	case "d":
+		return from.AddDate(0, 0, r.Interval), nil
+	case "w":
+		return from.AddDate(0, 0, r.Interval*7), nil
+	case "m":
+		return from.AddDate(0, r.Interval, 0), nil
+	case "y":
+		return from.AddDate(r.Interval, 0, 0), nil
+	}
+
+	date := from
+	for i := 0; i < maxSearchDays; i++ {
+		date = date.AddDate(0, 0, 1)
+		if r.matches(date) {
+			return date, nil
+		}
+	}
+	return time.Time{}, ErrInvalidRecurrence
+}
+
+func (r *Recurrence) matches(date time.Time) bool {
+	switch {
+	case len(r.Weekdays) > 0:
+		for _, wd := range r.Weekdays {
+			if date.Weekday() == wd {
+				return true
+			}
+		}
+		return false
+	case r.Nth == -1:
+		return date.Weekday() == r.Weekday && date.AddDate(0, 0, 7).Month() != date.Month()
+	case r.Nth > 0:
+		return date.Weekday() == r.Weekday && (date.Day()-1)/7+1 == r.Nth
+	case r.LastDay:
+		return date.AddDate(0, 0, 1).Month() != date.Month()
+	case r.LastBusinessDay:
+		if !isBusinessDay(date) {
+			return false
+		}
+		for d := date.AddDate(0, 0, 1); d.Month() == date.Month(); d = d.AddDate(0, 0, 1) {
+			if isBusinessDay(d) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func isBusinessDay(date time.Time) bool {
+	return date.Weekday() != time.Saturday && date.Weekday() != time.Sunday
+}
+
+// ValidateRecurrence checks the recurrence tags of task.
+func ValidateRecurrence(task *todotxt.Task) error {
+	if v, ok := task.AdditionalTags[KeyRec]; ok {
+		if _, err := ParseRecurrenceRule(v); err != nil {
+			return err
+		}
+	}
+	if v, ok := task.AdditionalTags[KeyRecUntil]; ok {
+		if _, err := time.ParseInLocation(todotxt.DateLayout, v, time.Local); err != nil {
+			return errors.New("invalid " + KeyRecUntil + ": " + v)
+		}
+	}
+	if v, ok := task.AdditionalTags[KeyRecCount]; ok {
+		if n, err := strconv.Atoi(v); err != nil || n <= 0 {
+			return errors.New("invalid " + KeyRecCount + ": " + v)
+		}
+	}
+	if v, ok := task.AdditionalTags[KeyRecSkip]; ok {
+		for _, s := range strings.Split(v, ",") {
+			if _, err := time.ParseInLocation(todotxt.DateLayout, s, time.Local); err != nil {
+				return errors.New("invalid " + KeyRecSkip + ": " + v)
+			}
+		}
+	}
+	if v, ok := task.AdditionalTags[KeyRecSnooze]; ok {
+		if _, err := time.ParseInLocation(todotxt.DateLayout, v, time.Local); err != nil {
+			return errors.New("invalid " + KeyRecSnooze + ": " + v)
+		}
+	}
+	return nil
+}
+
+// IsRecurrenceEnded reports whether the recurrence of task has no more occurrence
+// at next, when the series already has count tasks.
+func IsRecurrenceEnded(task *todotxt.Task, next time.Time, count int) bool {
+	if v, ok := task.AdditionalTags[KeyRecUntil]; ok {
+		until, err := time.ParseInLocation(todotxt.DateLayout, v, time.Local)
+		if err == nil && next.After(until) {
+			return true
+		}
+	}
+	if v, ok := task.AdditionalTags[KeyRecCount]; ok {
+		n, err := strconv.Atoi(v)
+		if err == nil && count >= n {
+			return true
+		}
+	}
+	return false
+}
+
+// SkippedOccurrences returns the dates of the skip tag of task.
+func SkippedOccurrences(task *todotxt.Task) []time.Time {
+	dates := []time.Time{}
+	v, ok := task.AdditionalTags[KeyRecSkip]
+	if !ok {
+		return dates
+	}
+	for _, s := range strings.Split(v, ",") {
+		if date, err := time.ParseInLocation(todotxt.DateLayout, s, time.Local); err == nil {
+			dates = append(dates, date)
+		}
+	}
+	return dates
+}
+
+func isSkipped(date time.Time, skipped []time.Time) bool {
+	for _, s := range skipped {
+		if s.Format(todotxt.DateLayout) == date.Format(todotxt.DateLayout) {
+			return true
+		}
+	}
+	return false
+}
+
+// SkipOccurrence adds date to the skip tag of task.
+func SkipOccurrence(task *todotxt.Task, date time.Time) {
+	if task.AdditionalTags == nil {
+		task.AdditionalTags = map[string]string{}
+	}
+	if v, ok := task.AdditionalTags[KeyRecSkip]; ok && v != "" {
+		if isSkipped(date, SkippedOccurrences(task)) {
+			return
+		}
+		task.AdditionalTags[KeyRecSkip] = v + "," + date.Format(todotxt.DateLayout)
+	} else {
+		task.AdditionalTags[KeyRecSkip] = date.Format(todotxt.DateLayout)
+	}
+}
+
+// PruneOccurrenceTags removes the skip and snooze tags which no longer affect
+// the occurrences after date. It is used for a newly opened task of the series.
+func PruneOccurrenceTags(task *todotxt.Task, date time.Time) {
+	delete(task.AdditionalTags, KeyRecSnooze)
+
+	remains := []string{}
+	for _, s := range SkippedOccurrences(task) {
+		if s.After(date) {
+			remains = append(remains, s.Format(todotxt.DateLayout))
+		}
+	}
+	if len(remains) == 0 {
+		delete(task.AdditionalTags, KeyRecSkip)
+	} else {
+		task.AdditionalTags[KeyRecSkip] = strings.Join(remains, ",")
+	}
+}
+
+// NextOccurrence is ParseRecurrence which respects the skip and snooze tags.
+func NextOccurrence(task *todotxt.Task) (time.Time, error) {
+	next, err := ParseRecurrence(task)
+	if err != nil || next.IsZero() {
+		return next, err
+	}
+	if v, ok := task.AdditionalTags[KeyRecSnooze]; ok {
+		if date, err := time.ParseInLocation(todotxt.DateLayout, v, time.Local); err == nil {
+			return date, nil
+		}
+	}
+
+	r, err := ParseRecurrenceRule(task.AdditionalTags[KeyRec])
+	if err != nil {
+		return next, err
+	}
+	return nextNotSkipped(r, next, SkippedOccurrences(task))
+}
+
+func nextNotSkipped(r *Recurrence, date time.Time, skipped []time.Time) (time.Time, error) {
+	var err error
+	for i := 0; i <= len(skipped) && isSkipped(date, skipped); i++ {
+		if date, err = r.Next(date); err != nil {
+			return date, err
+		}
+	}
+	return date, nil
+}
+
+// Occurrences returns up to n next dates of the series of task.
+// count is the number of tasks which the series already has.
+func Occurrences(task *todotxt.Task, n, count int) ([]time.Time, error) {
+	dates := []time.Time{}
+	v, ok := task.AdditionalTags[KeyRec]
+	if !ok {
+		return dates, nil
+	}
+	r, err := ParseRecurrenceRule(v)
+	if err != nil {
+		return nil, err
+	}
+
+	next, err := NextOccurrence(task)
+	if err != nil {
+		return nil, err
+	}
+	skipped := SkippedOccurrences(task)
+	for len(dates) < n {
+		if IsRecurrenceEnded(task, next, count+len(dates)) {
+			break
+		}
+		dates = append(dates, next)
+		if next, err = r.Next(next); err != nil {
+			return dates, err
+		}
+		if next, err = nextNotSkipped(r, next, skipped); err != nil {
+			return dates, err
+		}
+	}
+	return dates, nil
+}
+
+// SkipNextOccurrence skips the next occurrence of task, including a snoozed one,
+// and returns its date.
+func SkipNextOccurrence(task *todotxt.Task) (time.Time, error) {
+	next, err := NextOccurrence(task)
+	if err != nil {
+		return next, err
+	}
+	if snooze, ok := task.AdditionalTags[KeyRecSnooze]; ok {
+		// the snoozed occurrence replaces the original one
+		delete(task.AdditionalTags, KeyRecSnooze)
+		original, err := NextOccurrence(task)
+		if err != nil {
+			// 失敗したときはタスクを変更しない
+			task.AdditionalTags[KeyRecSnooze] = snooze
+			return next, err
+		}
+		SkipOccurrence(task, original)
+		return next, nil
+	}
+	SkipOccurrence(task, next)
+	return next, nil
+}
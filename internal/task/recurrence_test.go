@@ -0,0 +1,236 @@
+package task
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1set/todotxt"
+)
+
+func mustParseTask(t *testing.T, line string) *todotxt.Task {
+	t.Helper()
+	task, err := todotxt.ParseTask(line)
+	if err != nil {
+		t.Fatalf("ParseTask(%q): %v", line, err)
+	}
+	return task
+}
+
+func mustParseDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	date, err := time.ParseInLocation(todotxt.DateLayout, s, time.Local)
+	if err != nil {
+		t.Fatalf("ParseInLocation(%q): %v", s, err)
+	}
+	return date
+}
+
+func formatDates(dates []time.Time) string {
+	s := []string{}
+	for _, d := range dates {
+		s = append(s, d.Format(todotxt.DateLayout))
+	}
+	return strings.Join(s, " ")
+}
+
+func TestParseRecurrenceRule(t *testing.T) {
+	tests := []struct {
+		rule string
+		want *Recurrence
+	}{
+		{"3d", &Recurrence{Interval: 3, Period: "d"}},
+		{"2w", &Recurrence{Interval: 2, Period: "w"}},
+		{"1m", &Recurrence{Interval: 1, Period: "m"}},
+		{"1y", &Recurrence{Interval: 1, Period: "y"}},
+		{"1d*", &Recurrence{FromCompletion: true, Interval: 1, Period: "d"}},
+		{"weekday", &Recurrence{Weekdays: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}}},
+		{"bday", &Recurrence{Weekdays: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}}},
+		{"mon,thu", &Recurrence{Weekdays: []time.Weekday{time.Monday, time.Thursday}}},
+		{"MON", &Recurrence{Weekdays: []time.Weekday{time.Monday}}},
+		{"2tue", &Recurrence{Nth: 2, Weekday: time.Tuesday}},
+		{"lastfri", &Recurrence{Nth: -1, Weekday: time.Friday}},
+		{"lastday", &Recurrence{LastDay: true}},
+		{"lastbday*", &Recurrence{FromCompletion: true, LastBusinessDay: true}},
+
+		{"", nil},
+		{"*", nil},
+		{"d", nil},
+		{"0d", nil},
+		{"-1d", nil},
+		{"3x", nil},
+		{"6tue", nil},
+		{"firstmon", nil},
+		{"mon,xyz", nil},
+		{"mon,,thu", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			got, err := ParseRecurrenceRule(tt.rule)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("got %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"days", "2026-01-31 a rec:1d", "2026-02-01"},
+		{"weeks", "2026-10-18 a rec:2w", "2026-11-01"},
+		{"months", "2026-02-15 a rec:1m", "2026-03-15"},
+		{"years", "2026-10-18 a rec:1y", "2027-10-18"},
+		{"from the created date", "x 2026-10-20 2026-10-18 a rec:3d", "2026-10-21"},
+		{"from the completion", "x 2026-10-20 2026-10-18 a rec:3d*", "2026-10-23"},
+		{"open task from the completion", "2026-10-18 a rec:3d*", "2026-10-21"},
+		{"business day over a weekend", "2026-10-16 a rec:weekday", "2026-10-19"},
+		{"listed days", "2026-10-18 a rec:mon,thu", "2026-10-19"},
+		{"listed days next", "2026-10-19 a rec:mon,thu", "2026-10-22"},
+		{"nth weekday", "2026-10-01 a rec:2tue", "2026-10-13"},
+		{"nth weekday of the next month", "2026-10-13 a rec:2tue", "2026-11-10"},
+		{"last weekday", "2026-10-01 a rec:lastfri", "2026-10-30"},
+		{"last weekday of the next month", "2026-10-30 a rec:lastfri", "2026-11-27"},
+		{"last day", "2026-01-30 a rec:lastday", "2026-01-31"},
+		{"last day of february", "2026-01-31 a rec:lastday", "2026-02-28"},
+		{"last day of a leap february", "2028-02-01 a rec:lastday", "2028-02-29"},
+		{"last business day before a weekend", "2026-01-01 a rec:lastbday", "2026-01-30"},
+		{"last business day of the next month", "2026-01-30 a rec:lastbday", "2026-02-27"},
+		{"skipped", "2026-10-18 a rec:1d skip:2026-10-19", "2026-10-20"},
+		{"skipped twice", "2026-10-18 a rec:1d skip:2026-10-19,2026-10-20", "2026-10-21"},
+		{"skipped in the past", "2026-10-18 a rec:1d skip:2026-10-01", "2026-10-19"},
+		{"snoozed", "2026-10-18 a rec:1d snooze:2026-10-25", "2026-10-25"},
+		{"no recurrence", "2026-10-18 a", "0001-01-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NextOccurrence(mustParseTask(t, tt.line))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Format(todotxt.DateLayout) != tt.want {
+				t.Errorf("got %s, want %s", got.Format(todotxt.DateLayout), tt.want)
+			}
+		})
+	}
+}
+
+func TestNextOccurrenceInvalid(t *testing.T) {
+	if _, err := NextOccurrence(mustParseTask(t, "2026-10-18 a rec:xx")); err == nil {
+		t.Error("got no error for an invalid rule")
+	}
+}
+
+func TestIsRecurrenceEnded(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		next  string
+		count int
+		want  bool
+	}{
+		{"no limit", "2026-10-18 a rec:1d", "2030-01-01", 100, false},
+		{"on the until date", "2026-10-18 a rec:1d until:2026-10-20", "2026-10-20", 0, false},
+		{"after the until date", "2026-10-18 a rec:1d until:2026-10-20", "2026-10-21", 0, true},
+		{"below the count", "2026-10-18 a rec:1d count:3", "2026-10-19", 2, false},
+		{"at the count", "2026-10-18 a rec:1d count:3", "2026-10-19", 3, true},
+		{"either limit", "2026-10-18 a rec:1d count:3 until:2026-10-20", "2026-10-21", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := IsRecurrenceEnded(mustParseTask(t, tt.line), mustParseDate(t, tt.next), tt.count)
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		n     int
+		count int
+		want  string
+	}{
+		{"days", "2026-10-18 a rec:1d", 3, 0, "2026-10-19 2026-10-20 2026-10-21"},
+		{"skipped", "2026-10-18 a rec:1d skip:2026-10-20", 3, 0, "2026-10-19 2026-10-21 2026-10-22"},
+		{"until", "2026-10-18 a rec:1d until:2026-10-20", 5, 0, "2026-10-19 2026-10-20"},
+		{"count", "2026-10-18 a rec:1d count:3", 5, 1, "2026-10-19 2026-10-20"},
+		{"snoozed", "2026-10-18 a rec:1d snooze:2026-10-25", 3, 0, "2026-10-25 2026-10-26 2026-10-27"},
+		{"last days", "2026-01-15 a rec:lastday", 3, 0, "2026-01-31 2026-02-28 2026-03-31"},
+		{"listed days", "2026-10-18 a rec:mon,thu", 4, 0, "2026-10-19 2026-10-22 2026-10-26 2026-10-29"},
+		{"no recurrence", "2026-10-18 a", 3, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Occurrences(mustParseTask(t, tt.line), tt.n, tt.count)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if formatDates(got) != tt.want {
+				t.Errorf("got %q, want %q", formatDates(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestSkipNextOccurrence(t *testing.T) {
+	tests := []struct {
+		name       string
+		line       string
+		want       string
+		wantSkip   string
+		wantSnooze bool
+	}{
+		{"first skip", "2026-10-18 a rec:1d", "2026-10-19", "2026-10-19", false},
+		{"second skip", "2026-10-18 a rec:1d skip:2026-10-19", "2026-10-20", "2026-10-19,2026-10-20", false},
+		// the snoozed occurrence is skipped in place of the original one
+		{"snoozed", "2026-10-18 a rec:1d snooze:2026-10-25", "2026-10-25", "2026-10-19", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := mustParseTask(t, tt.line)
+			got, err := SkipNextOccurrence(task)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Format(todotxt.DateLayout) != tt.want {
+				t.Errorf("got %s, want %s", got.Format(todotxt.DateLayout), tt.want)
+			}
+			if task.AdditionalTags[KeyRecSkip] != tt.wantSkip {
+				t.Errorf("skip: got %q, want %q", task.AdditionalTags[KeyRecSkip], tt.wantSkip)
+			}
+			if _, ok := task.AdditionalTags[KeyRecSnooze]; ok != tt.wantSnooze {
+				t.Errorf("snooze: got %v, want %v", ok, tt.wantSnooze)
+			}
+		})
+	}
+}
+
+func TestSkipNextOccurrenceInvalid(t *testing.T) {
+	task := mustParseTask(t, "2026-10-18 a rec:xx snooze:2026-10-25")
+	if _, err := SkipNextOccurrence(task); err == nil {
+		t.Fatal("got no error for an invalid rule")
+	}
+	if task.AdditionalTags[KeyRecSnooze] != "2026-10-25" {
+		t.Errorf("snooze: got %q, want the task unchanged", task.AdditionalTags[KeyRecSnooze])
+	}
+	if _, ok := task.AdditionalTags[KeyRecSkip]; ok {
+		t.Error("skip: got a tag, want the task unchanged")
+	}
+}
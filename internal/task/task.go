@@ -1,39 +1,74 @@
 package task
 
 import (
-	"errors"
 	"github.com/1set/todotxt"
-	"strconv"
+	"sort"
 	"strings"
 	"time"
 )
 
 const (
-	KeyRec        = "rec"   // 繰り返し情報
-	KeyRecID      = "recid" // 繰り返し情報のID
-	KeyNote       = "note"  // 備考
-	KeyStartDoing = "doing" // Doingにした日時
+	KeyRec        = "rec"    // 繰り返し情報
+	KeyRecID      = "recid"  // 繰り返し情報のID
+	KeyNote       = "note"   // 備考
+	KeyStartDoing = "doing"  // Doingにした日時
+	KeyDue        = "due"    // 期限
+	KeyRecUntil   = "until"  // 繰り返しの終了日
+	KeyRecCount   = "count"  // 繰り返しの回数
+	KeyRecSkip    = "skip"   // スキップする繰り返し日
+	KeyRecSnooze  = "snooze" // 次の繰り返しを延期した日
+	KeyID         = "id"     // タスクの固定ID
+	KeyParent     = "parent" // 親タスクのID
+	KeyDep        = "dep"    // 先行タスクのID(カンマ区切り)
+	KeyStatus     = "status" // 所属する列
+	KeyClock      = "clock"  // 作業時間の記録(開始/終了のカンマ区切り)
 )
 
+// GetProjectName returns the first project of t, or "" if t has no project
 func GetProjectName(t todotxt.Task) string {
-	projects := t.Projects
-	if len(projects) == 0 || projects == nil {
-		panic("no project")
+	if len(t.Projects) == 0 {
+		return ""
 	}
-	return projects[0]
+	return t.Projects[0]
+}
+
+// GetProjectNames returns the projects of t without duplicates
+func GetProjectNames(t todotxt.Task) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, p := range t.Projects {
+		if p != "" && !seen[p] {
+			names = append(names, p)
+			seen[p] = true
+		}
+	}
+	return names
 }
 
 func GetTaskKey(t todotxt.Task) string {
 	if recID, ok := t.AdditionalTags[KeyRecID]; ok {
 		return recID
 	}
-	return t.Todo + t.Priority + GetProjectName(t)
+	// 保存時にプロジェクトの順序が変わっても同じキーになるようにソートする
+	projects := GetProjectNames(t)
+	sort.Strings(projects)
+	return t.Todo + t.Priority + strings.Join(projects, " ")
 }
 
 func ReplaceInvalidTag(field string) string {
 	validTags := []string{
 		KeyRec,
 		KeyNote,
+		KeyDue,
+		KeyRecUntil,
+		KeyRecCount,
+		KeyRecSkip,
+		KeyRecSnooze,
+		KeyID,
+		KeyParent,
+		KeyDep,
+		KeyStatus,
+		KeyClock,
 	}
 	if !strings.Contains(field, ":") {
 		return field
@@ -51,7 +86,7 @@ func ReplaceInvalidTag(field string) string {
 	return field
 }
 
-func ToTodo(task *todotxt.Task) {
+func ToTodo(task *todotxt.Task, date time.Time) {
 	for i, c := range task.Contexts {
 		if c == "doing" {
 			task.Contexts = append(task.Contexts[:i], task.Contexts[i+1:]...)
@@ -62,6 +97,7 @@ func ToTodo(task *todotxt.Task) {
 	if task.AdditionalTags != nil {
 		delete(task.AdditionalTags, KeyStartDoing)
 	}
+	ClockOut(task, ClockTime(date, time.Now()))
 
 	task.Reopen()
 }
@@ -82,6 +118,7 @@ func ToDoing(task *todotxt.Task, date time.Time) {
 		task.AdditionalTags = make(map[string]string)
 	}
 	task.AdditionalTags[KeyStartDoing] = date.Format(todotxt.DateLayout)
+	ClockIn(task, ClockTime(date, time.Now()))
 
 	task.Reopen()
 }
@@ -93,41 +130,25 @@ func ToDone(task *todotxt.Task, date time.Time) {
 			break
 		}
 	}
+	ClockOut(task, ClockTime(date, time.Now()))
 	task.Completed = true
 	task.CompletedDate = date
 }
 
 func ParseRecurrence(task *todotxt.Task) (time.Time, error) {
-	isRepeatOnCompletion := false
 	nextOpenTime := time.Time{}
 	if task.HasAdditionalTags() {
 		if v, ok := task.AdditionalTags[KeyRec]; ok {
-			isRepeatOnCompletion = strings.HasSuffix(v, "*")
-			if isRepeatOnCompletion {
-				v = v[:len(v)-1]
-			}
-			num, err := strconv.Atoi(v[:len(v)-1])
+			r, err := ParseRecurrenceRule(v)
 			if err != nil {
 				return nextOpenTime, err
 			}
-			period := v[len(v)-1:]
-			if isRepeatOnCompletion && task.Completed {
+			if r.FromCompletion && task.Completed {
 				nextOpenTime = task.CompletedDate
 			} else {
 				nextOpenTime = task.CreatedDate
 			}
-			switch period {
-			case "d":
-				nextOpenTime = nextOpenTime.AddDate(0, 0, num)
-			case "w":
-				nextOpenTime = nextOpenTime.AddDate(0, 0, num*7)
-			case "m":
-				nextOpenTime = nextOpenTime.AddDate(0, num, 0)
-			case "y":
-				nextOpenTime = nextOpenTime.AddDate(num, 0, 0)
-			default:
-				return nextOpenTime, errors.New("invalid recurrence period")
-			}
+			return r.Next(nextOpenTime)
 		}
 	}
 	return nextOpenTime, nil
@@ -0,0 +1,115 @@
+package tui
+
+import (
+	"github.com/1set/todotxt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	db "github.com/apxxxxxxe/kanban.txt/internal/db"
+	tsk "github.com/apxxxxxxe/kanban.txt/internal/task"
+)
+
+const (
+	archivePurge = "Purge"
+	// the hint of the keys of the archive page in its title
+	archiveKeysHint = " (u: unarchive, D: purge, q: close)"
+	// the days suggested when archiving the done tasks of a project
+	defaultArchiveDays = 30
+)
+
+var archiveHeaders = []string{"Task", "Project", "Recurrence", "Last completed"}
+
+// showArchive lists the archived series and tasks
+func (t *Tui) showArchive() {
+	t.resetArchiveWidget()
+	t.Pages.ShowPage(archivePage)
+	t.pushFocus(t.ArchiveWidget.Box)
+}
+
+func (t *Tui) resetArchiveWidget() {
+	row, _ := t.ArchiveWidget.GetSelection()
+	t.ArchiveWidget.Clear()
+	t.ArchiveWidget.SetTitle(archiveWidgetTitle + archiveKeysHint)
+
+	for i, h := range archiveHeaders {
+		t.ArchiveWidget.SetCell(0, i, tview.NewTableCell(h).SetTextColor(tcell.ColorGray).SetSelectable(false))
+	}
+	t.ArchiveWidget.SetFixed(1, 0)
+
+	entries := t.DB.ArchivedEntries()
+	if len(entries) == 0 {
+		t.ArchiveWidget.SetCell(1, 0, tview.NewTableCell("No archived tasks").SetSelectable(false))
+		return
+	}
+	for i, e := range entries {
+		todo, project, rec, completed := "(no task left)", "", "", "-"
+		if e.Task != nil {
+			todo = e.Task.Todo
+			project = db.ProjectNames(e.Task)[0]
+			rec = e.Task.AdditionalTags[tsk.KeyRec]
+		}
+		if !e.LastCompleted.IsZero() {
+			completed = e.LastCompleted.Format(todotxt.DateLayout)
+		}
+		for j, text := range []string{todo, project, rec, completed} {
+			cell := tview.NewTableCell(tview.Escape(text)).SetReference(e.Key)
+			if j == 0 {
+				cell.SetExpansion(1)
+			}
+			t.ArchiveWidget.SetCell(i+1, j, cell)
+		}
+	}
+
+	if row < 1 {
+		row = 1
+	} else if row > len(entries) {
+		row = len(entries)
+	}
+	t.ArchiveWidget.Select(row, 0)
+}
+
+// selectedArchive returns the key of the selected entry, or "" if there is none
+func (t *Tui) selectedArchive() string {
+	row, _ := t.ArchiveWidget.GetSelection()
+	key, _ := t.ArchiveWidget.GetCell(row, 0).GetReference().(string)
+	return key
+}
+
+func (t *Tui) archiveWidgetInputCaptureFunc(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() == tcell.KeyEscape || event.Rune() == 'q' {
+		t.Pages.HidePage(archivePage)
+		t.popFocus()
+		return nil
+	}
+
+	switch event.Rune() {
+	case 'u':
+		key := t.selectedArchive()
+		if key == "" {
+			return nil
+		}
+		t.recordHistory()
+		t.DB.Unarchive(key)
+		t.refreshProjects()
+		t.resetArchiveWidget()
+		t.Notify("Unarchived", false)
+		return nil
+	case 'D':
+		key := t.selectedArchive()
+		if key == "" {
+			return nil
+		}
+		t.confirm("Delete the archived tasks permanently?", []string{archivePurge, confirmCancel}, func(label string) {
+			if label != archivePurge {
+				return
+			}
+			t.recordHistory()
+			t.DB.PurgeArchived(key)
+			t.refreshProjects()
+			t.resetArchiveWidget()
+			t.Notify("Purged", false)
+		})
+		return nil
+	}
+	return event
+}
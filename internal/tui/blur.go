@@ -3,24 +3,13 @@ package tui
 import "github.com/rivo/tview"
 
 func (t *Tui) setBlurFunc() {
-	t.TodoPane.SetBlurFunc(t.todoPaneBlurFunc)
-	t.DoingPane.SetBlurFunc(t.doingPaneBlurFunc)
-	t.DonePane.SetBlurFunc(t.donePaneBlurFunc)
+	for _, pane := range t.Panes {
+		table := pane.Table
+		pane.SetBlurFunc(func() { t.tableBlurFunc(table) })
+	}
 	t.DescriptionWidget.SetBlurFunc(t.descriptionWidgetBlurFunc)
 }
 
-func (t *Tui) todoPaneBlurFunc() {
-	t.tableBlurFunc(t.TodoPane.Table)
-}
-
-func (t *Tui) doingPaneBlurFunc() {
-	t.tableBlurFunc(t.DoingPane.Table)
-}
-
-func (t *Tui) donePaneBlurFunc() {
-	t.tableBlurFunc(t.DonePane.Table)
-}
-
 func (t *Tui) descriptionWidgetBlurFunc() {
 	t.DescriptionWidget.Clear()
 	t.tableBlurFunc(t.DescriptionWidget)
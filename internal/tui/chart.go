@@ -0,0 +1,136 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	db "github.com/apxxxxxxe/kanban.txt/internal/db"
+)
+
+const (
+	// the number of the days plotted in the chart page
+	chartDays = 28
+	// the number of the rows of a chart
+	chartHeight = 10
+	// the interval of the date labels under a chart
+	chartLabelDays = 7
+)
+
+type chartSeries struct {
+	name   string
+	color  string
+	values []int
+}
+
+// stackedChart plots series stacked from the bottom, one column of two characters for each day
+func stackedChart(history []db.FlowDay, series []chartSeries) string {
+	max := 1
+	for i := range history {
+		total := 0
+		for _, s := range series {
+			total += s.values[i]
+		}
+		if total > max {
+			max = total
+		}
+	}
+
+	text := ""
+	for row := chartHeight; row > 0; row-- {
+		label := ""
+		switch row {
+		case chartHeight:
+			label = fmt.Sprint(max)
+		case 1:
+			label = "0"
+		}
+		text += fmt.Sprintf("%4s │", label)
+		for i := range history {
+			cell := "  "
+			total := 0
+			for _, s := range series {
+				total += s.values[i]
+				// 値が1以上なら最低1行は塗る
+				if height := (total*chartHeight + max - 1) / max; height >= row && s.values[i] > 0 {
+					cell = "[" + s.color + "]██[-]"
+					break
+				}
+			}
+			text += cell
+		}
+		text += "\n"
+	}
+
+	text += "     └" + strings.Repeat("──", len(history)) + "\n      "
+	for i := 0; i < len(history); i += chartLabelDays {
+		label := history[i].Date.Format("01-02")
+		text += label + strings.Repeat(" ", chartLabelDays*2-len(label))
+	}
+	text += "\n"
+
+	legend := []string{}
+	for _, s := range series {
+		legend = append(legend, "["+s.color+"]██[-] "+s.name)
+	}
+	return text + "      " + strings.Join(legend, "  ") + "\n"
+}
+
+// showCharts shows the burndown and the cumulative flow of the current project until the selected day
+func (t *Tui) showCharts() {
+	p := t.ProjectPane.GetCurrentProject()
+	if p == nil {
+		p = &db.Project{ProjectName: db.AllTasks}
+	}
+	to := t.getSelectingDate()
+	history, err := t.DB.FlowHistory(p, to.AddDate(0, 0, 1-chartDays), to)
+	if err != nil {
+		t.Notify(err.Error(), true)
+		return
+	}
+
+	todo, doing, done, remaining := []int{}, []int{}, []int{}, []int{}
+	for _, f := range history {
+		todo = append(todo, f.Todo)
+		doing = append(doing, f.Doing)
+		done = append(done, f.Done)
+		remaining = append(remaining, f.Remaining())
+	}
+
+	// 列名は設定に合わせる
+	columns := t.DB.GetColumns()
+	columnName := func(index int, name string) string {
+		if index < 0 {
+			return name
+		}
+		return columns[index].Name
+	}
+
+	text := "[#a0a0a0::b]" + tview.Escape(p.ProjectName) + "[-::-]\n\n"
+	text += "[#a0a0a0::b]Burndown[-::-]\n"
+	text += stackedChart(history, []chartSeries{
+		{"Remaining", "#ff8080", remaining},
+	})
+	text += "\n[#a0a0a0::b]Cumulative flow[-::-]\n"
+	text += stackedChart(history, []chartSeries{
+		{columnName(t.DB.DoneColumn(), "Done"), "#80c080", done},
+		{columnName(t.DB.DoingColumn(), "Doing"), "#e0c060", doing},
+		{columnName(t.DB.DefaultColumn(), "Todo"), "#80a0ff", todo},
+	})
+
+	text += "\nPress Esc or q to close"
+	t.ChartWidget.SetText(text).ScrollToBeginning()
+	t.Pages.ShowPage(chartPage)
+	t.pushFocus(t.ChartWidget.Box)
+}
+
+func (t *Tui) chartWidgetInputCaptureFunc(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() == tcell.KeyEscape || event.Rune() == 'q' {
+		t.Pages.HidePage(chartPage)
+		t.popFocus()
+		return nil
+	}
+	return event
+}
@@ -0,0 +1,169 @@
+package tui
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	db "github.com/apxxxxxxe/kanban.txt/internal/db"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// the number of colors offered in the color popup besides the automatic one
+const colorChoices = 12
+
+// isPaintable reports whether name is colored
+func isPaintable(c *db.ColorConfig, name string) bool {
+	return c != nil && c.EnablePaint && name != db.AllTasks && name != db.NoProject
+}
+
+// projectColor returns the color of the project, chosen in the color popup or derived from its name
+func projectColor(c *db.ColorConfig, name string) tcell.Color {
+	if v, ok := c.ProjectColors[name]; ok {
+		if color := tcell.GetColor(v); color != tcell.ColorDefault {
+			return color
+		}
+	}
+	return nameColor(c, name)
+}
+
+// nameColor derives a color from the hash of name within the HSL ranges of the config
+func nameColor(c *db.ColorConfig, name string) tcell.Color {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	sum := h.Sum32()
+
+	pick := func(v uint32, min, max int) float64 {
+		if max < min {
+			min, max = max, min
+		}
+		return float64(min + int(v%uint32(max-min+1)))
+	}
+	hue := pick(sum, c.MinHue, c.MaxHue)
+	saturation := pick(sum>>10, c.MinSaturatio, c.MaxSaturatio)
+	lightness := pick(sum>>20, c.MinLightness, c.MaxLightness)
+	return hslToColor(hue, saturation/100, lightness/100)
+}
+
+// colorChoice returns the i-th of colorChoices colors spread over the hue range of the config
+func colorChoice(c *db.ColorConfig, i int) tcell.Color {
+	hue := float64(c.MinHue) + float64(c.MaxHue-c.MinHue)*float64(i)/colorChoices
+	saturation := float64(c.MinSaturatio+c.MaxSaturatio) / 2 / 100
+	lightness := float64(c.MinLightness+c.MaxLightness) / 2 / 100
+	return hslToColor(hue, saturation, lightness)
+}
+
+// hslToColor converts h (0 to 360), s and l (0 to 1) into a color
+func hslToColor(h, s, l float64) tcell.Color {
+	h = math.Mod(h, 360) / 360
+	s = math.Max(0, math.Min(1, s))
+	l = math.Max(0, math.Min(1, l))
+	if s == 0 {
+		v := int32(math.Round(l * 255))
+		return tcell.NewRGBColor(v, v, v)
+	}
+
+	var q float64
+	if l < 0.5 {
+		q = l * (1 + s)
+	} else {
+		q = l + s - l*s
+	}
+	p := 2*l - q
+	hueToRGB := func(t float64) int32 {
+		if t < 0 {
+			t++
+		} else if t > 1 {
+			t--
+		}
+		var v float64
+		switch {
+		case t < 1.0/6:
+			v = p + (q-p)*6*t
+		case t < 1.0/2:
+			v = q
+		case t < 2.0/3:
+			v = p + (q-p)*(2.0/3-t)*6
+		default:
+			v = p
+		}
+		return int32(math.Round(v * 255))
+	}
+	return tcell.NewRGBColor(hueToRGB(h+1.0/3), hueToRGB(h), hueToRGB(h-1.0/3))
+}
+
+func colorToHex(c tcell.Color) string {
+	return fmt.Sprintf("#%06x", c.Hex())
+}
+
+// showColorPopup opens the popup to choose the color of the current project
+func (t *Tui) showColorPopup() {
+	project := t.ProjectPane.GetCurrentProject()
+	if project == nil || !isPaintable(t.Config.Color, project.ProjectName) {
+		t.Notify("This project cannot be colored", true)
+		return
+	}
+
+	name := tview.Escape(project.ProjectName)
+	t.ColorWidget.Clear()
+	t.ColorWidget.SetTitle(colorWidgetTitle + ": " + name)
+	auto := nameColor(t.Config.Color, project.ProjectName)
+	t.ColorWidget.SetCell(0, 0, tview.NewTableCell(name+" (auto)").SetTextColor(auto).SetReference(""))
+	selected := 0
+	current, hasCurrent := t.Config.Color.ProjectColors[project.ProjectName]
+	for i := 0; i < colorChoices; i++ {
+		color := colorChoice(t.Config.Color, i)
+		hex := colorToHex(color)
+		t.ColorWidget.SetCell(i+1, 0, tview.NewTableCell(name+" "+hex).SetTextColor(color).SetReference(hex))
+		if hasCurrent && current == hex {
+			selected = i + 1
+		}
+	}
+	if hasCurrent && selected == 0 {
+		// a color written in config.json by hand
+		color := tcell.GetColor(current)
+		t.ColorWidget.SetCell(colorChoices+1, 0, tview.NewTableCell(name+" "+current).SetTextColor(color).SetReference(current))
+		selected = colorChoices + 1
+	}
+	t.ColorWidget.Select(selected, 0)
+
+	t.Pages.ShowPage(colorTable)
+	t.pushFocus(t.ColorWidget.Box)
+}
+
+func (t *Tui) colorWidgetInputCaptureFunc(event *tcell.EventKey) *tcell.EventKey {
+	closePopup := func() {
+		t.Pages.HidePage(colorTable)
+		t.popFocus()
+	}
+
+	switch event.Key() {
+	case tcell.KeyEscape:
+		closePopup()
+		return nil
+	case tcell.KeyEnter:
+		project := t.ProjectPane.GetCurrentProject()
+		hex, _ := t.ColorWidget.GetCell(t.ColorWidget.GetSelection()).GetReference().(string)
+		closePopup()
+		if project == nil {
+			return nil
+		}
+
+		if t.Config.Color.ProjectColors == nil {
+			t.Config.Color.ProjectColors = map[string]string{}
+		}
+		if hex == "" {
+			delete(t.Config.Color.ProjectColors, project.ProjectName)
+		} else {
+			t.Config.Color.ProjectColors[project.ProjectName] = hex
+		}
+		if err := db.SaveConfig(t.Config); err != nil {
+			t.Notify(err.Error(), true)
+			return nil
+		}
+		t.refreshProjects()
+		return nil
+	}
+	return event
+}
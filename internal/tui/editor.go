@@ -0,0 +1,162 @@
+package tui
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/1set/todotxt"
+
+	db "github.com/apxxxxxxe/kanban.txt/internal/db"
+	tsk "github.com/apxxxxxxe/kanban.txt/internal/task"
+)
+
+const (
+	// the editor used when neither $VISUAL nor $EDITOR is set
+	defaultEditor = "vi"
+	// the button to apply an edit which moves the task out of its recurring series
+	editLeaveSeries = "Leave the series"
+)
+
+// editorCommand returns the command line of $VISUAL or $EDITOR, which may have arguments like "code -w"
+func editorCommand() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if args := strings.Fields(os.Getenv(env)); len(args) > 0 {
+			return args
+		}
+	}
+	return []string{defaultEditor}
+}
+
+// runEditor opens text in the editor with the app suspended and returns the edited text
+func (t *Tui) runEditor(text string) (string, error) {
+	f, err := os.CreateTemp("", "kanban-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(text + "\n"); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	args := editorCommand()
+	var runErr error
+	if !t.App.Suspend(func() {
+		cmd := exec.Command(args[0], append(args[1:], f.Name())...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		runErr = cmd.Run()
+	}) {
+		return "", errors.New("failed to suspend the screen")
+	}
+	if runErr != nil {
+		return "", runErr
+	}
+
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// editInEditor edits the note of the task in the description, or its whole line if line is true, in the editor
+func (t *Tui) editInEditor(line bool) {
+	task, err := getTaskFromCell(t.EditingCell)
+	if err != nil {
+		t.Notify(err.Error(), true)
+		return
+	}
+	pane := t.editingPane()
+
+	text := tsk.Note(task)
+	if line {
+		text = db.TaskString(task)
+	}
+	edited, err := t.runEditor(text)
+	if err != nil {
+		t.Notify(err.Error(), true)
+		return
+	}
+	if edited == text {
+		t.Notify("No changes", false)
+		return
+	}
+
+	if !line {
+		t.recordHistory()
+		tsk.SetNote(task, edited)
+		t.finishEditing(pane, task)
+		return
+	}
+
+	parsed, err := db.ParseTaskLine(edited)
+	if err != nil {
+		t.Notify(err.Error(), true)
+		return
+	}
+	if err := t.DB.CheckIDChange(task, parsed.AdditionalTags[tsk.KeyID]); err != nil {
+		t.Notify(err.Error(), true)
+		return
+	}
+	apply := func() {
+		old := *task
+		t.recordHistory()
+		// 行番号はファイル上の位置なので引き継ぐ
+		parsed.ID = task.ID
+		*task = *parsed
+		if task.AdditionalTags[tsk.KeyDep] != old.AdditionalTags[tsk.KeyDep] {
+			if err := t.DB.CheckDependencies(task); err != nil {
+				*task = old
+				t.Notify(err.Error(), true)
+				return
+			}
+		}
+		t.finishEditing(pane, task)
+	}
+
+	// recidが変わると別のシリーズとして扱われるので確認する
+	if isRecurring(task) && tsk.GetTaskKey(*task) != tsk.GetTaskKey(*parsed) {
+		t.confirm("The task will leave its recurring series. Continue?", []string{editLeaveSeries, confirmCancel}, func(label string) {
+			if label == editLeaveSeries {
+				apply()
+			}
+		})
+		return
+	}
+	apply()
+}
+
+// finishEditing redraws the board after task in pane is edited in the editor
+func (t *Tui) finishEditing(pane *TodoTable, task *todotxt.Task) {
+	t.refreshProjects()
+
+	// the cells are recreated by refreshProjects
+	if pane != nil {
+		selectTaskRow(pane, task)
+		t.EditingCell = pane.GetCell(pane.GetSelection())
+	}
+	t.Notify("Edited in "+editorCommand()[0], false)
+}
+
+// isRecurring reports whether t belongs to a recurring series
+func isRecurring(t *todotxt.Task) bool {
+	_, rec := t.AdditionalTags[tsk.KeyRec]
+	_, recID := t.AdditionalTags[tsk.KeyRecID]
+	return rec || recID
+}
+
+// selectTaskRow selects the row of task in pane if it is there
+func selectTaskRow(pane *TodoTable, task *todotxt.Task) {
+	for row := 0; row < pane.GetRowCount(); row++ {
+		if pane.GetCell(row, 0).GetReference() == task {
+			pane.Select(row, 0)
+			return
+		}
+	}
+	pane.AdjustSelection()
+}
@@ -1,34 +1,60 @@
 package tui
 
+import (
+	"fmt"
+
+	db "github.com/apxxxxxxe/kanban.txt/internal/db"
+)
+
 func (t *Tui) setFocusedFunc() {
-	t.TodoPane.SetFocusFunc(t.todoPaneInputFocusFunc)
-	t.DoingPane.SetFocusFunc(t.doingPaneInputFocusFunc)
-	t.DonePane.SetFocusFunc(t.donePaneInputFocusFunc)
-  t.DescriptionWidget.SetFocusFunc(t.descriptionWidgetInputFocusFunc)
+	for i := range t.Panes {
+		i := i
+		t.Panes[i].SetFocusFunc(func() { t.paneInputFocusFunc(i) })
+	}
+	t.DescriptionWidget.SetFocusFunc(t.descriptionWidgetInputFocusFunc)
 }
 
-func (t *Tui) todoPaneInputFocusFunc() {
-	t.TodoPane.SetTitle(todoPaneTitle)
-	t.DoingPane.SetTitle("[l]" + doingPaneTitle)
-	t.DonePane.SetTitle(donePaneTitle)
+// paneInputFocusFunc shows the keys to the neighbors of the focused pane in their titles
+func (t *Tui) paneInputFocusFunc(index int) {
+	t.HintedPane = index
+	t.updatePaneTitles()
 	t.DescriptionWidget.SetTitle("[D]" + descriptionWidgetTitle)
-	t.tableInputFocusFunc(t.TodoPane)
+	t.tableInputFocusFunc(t.Panes[index])
 }
 
-func (t *Tui) doingPaneInputFocusFunc() {
-	t.TodoPane.SetTitle("[h]" + todoPaneTitle)
-	t.DoingPane.SetTitle(doingPaneTitle)
-	t.DonePane.SetTitle("[l]" + donePaneTitle)
-	t.DescriptionWidget.SetTitle("[D]" + descriptionWidgetTitle)
-	t.tableInputFocusFunc(t.DoingPane)
+// updatePaneTitles sets the titles of the panes with the keys to the neighbors of HintedPane,
+// the WIP limits and the filter
+func (t *Tui) updatePaneTitles() {
+	for i, pane := range t.Panes {
+		title := t.columnTitle(i)
+		switch {
+		case t.HintedPane < 0:
+		case i == t.HintedPane-1:
+			title = "[h]" + title
+		case i == t.HintedPane+1:
+			title = "[l]" + title
+		}
+		pane.SetTitle(t.paneTitle(title))
+	}
 }
 
-func (t *Tui) donePaneInputFocusFunc() {
-	t.TodoPane.SetTitle(todoPaneTitle)
-	t.DoingPane.SetTitle("[h]" + doingPaneTitle)
-	t.DonePane.SetTitle(donePaneTitle)
-	t.DescriptionWidget.SetTitle("[D]" + descriptionWidgetTitle)
-	t.tableInputFocusFunc(t.DonePane)
+// columnTitle returns the name of the column at index followed by its WIP limit like "Doing (3/3)"
+func (t *Tui) columnTitle(index int) string {
+	column := t.DB.GetColumns()[index]
+	projectName := db.AllTasks
+	if p := t.ProjectPane.GetCurrentProject(); p != nil && p.View == nil {
+		projectName = p.ProjectName
+	}
+
+	count, limit := t.DB.ColumnLimit(projectName, index)
+	switch {
+	case limit == 0:
+		return column.Name
+	case count > limit:
+		return fmt.Sprintf("%s [#ff0000::b](%d/%d)[-::-]", column.Name, count, limit)
+	default:
+		return fmt.Sprintf("%s (%d/%d)", column.Name, count, limit)
+	}
 }
 
 func (t *Tui) tableInputFocusFunc(table *TodoTable) {
@@ -39,5 +65,5 @@ func (t *Tui) tableInputFocusFunc(table *TodoTable) {
 }
 
 func (t *Tui) descriptionWidgetInputFocusFunc() {
-  t.DescriptionWidget.SetSelectable(true, false)
+	t.DescriptionWidget.SetSelectable(true, false)
 }
@@ -0,0 +1,127 @@
+package tui
+
+import (
+	db "github.com/apxxxxxxe/kanban.txt/internal/db"
+)
+
+const maxHistory = 100
+
+type historyEntry struct {
+	snapshot    db.Snapshot
+	projectName string
+	isView      bool
+	pane        *TodoTable
+	cellText    string
+}
+
+type History struct {
+	undo []historyEntry
+	redo []historyEntry
+}
+
+func (t *Tui) focusedPane() *TodoTable {
+	if i := t.focusedColumn(); i >= 0 {
+		return t.Panes[i]
+	}
+	return nil
+}
+
+// focusedColumn returns the index of the focused pane, or -1
+func (t *Tui) focusedColumn() int {
+	for i, p := range t.Panes {
+		if p.HasFocus() {
+			return i
+		}
+	}
+	return -1
+}
+
+// editingPane returns the pane whose task is shown in the description widget
+func (t *Tui) editingPane() *TodoTable {
+	if t.EditingCell == nil {
+		return nil
+	}
+	for _, p := range t.Panes {
+		if p.GetRowCount() > 0 && p.GetCell(p.GetSelection()) == t.EditingCell {
+			return p
+		}
+	}
+	return nil
+}
+
+func (t *Tui) newHistoryEntry() historyEntry {
+	entry := historyEntry{snapshot: t.DB.Snapshot()}
+	if p := t.ProjectPane.GetCurrentProject(); p != nil {
+		entry.projectName = p.ProjectName
+		entry.isView = p.View != nil
+	}
+
+	pane := t.focusedPane()
+	if pane == nil {
+		pane = t.editingPane()
+	}
+	if pane != nil {
+		entry.pane = pane
+		if pane.GetRowCount() > 0 {
+			entry.cellText = pane.GetCell(pane.GetSelection()).Text
+		}
+	}
+	return entry
+}
+
+// recordHistory must be called just before the tasks are changed
+func (t *Tui) recordHistory() {
+	t.pushHistory(t.newHistoryEntry())
+}
+
+// pushHistory records entry taken before a change which may be rejected
+func (t *Tui) pushHistory(entry historyEntry) {
+	t.History.undo = append(t.History.undo, entry)
+	if len(t.History.undo) > maxHistory {
+		t.History.undo = t.History.undo[1:]
+	}
+	t.History.redo = nil
+}
+
+func (t *Tui) undo() {
+	t.stepHistory(&t.History.undo, &t.History.redo, "undo")
+}
+
+func (t *Tui) redo() {
+	t.stepHistory(&t.History.redo, &t.History.undo, "redo")
+}
+
+func (t *Tui) stepHistory(from, to *[]historyEntry, name string) {
+	current := t.newHistoryEntry()
+
+	// skip the entries which did not change anything
+	for len(*from) > 0 && (*from)[len(*from)-1].snapshot.Equal(current.snapshot) {
+		*from = (*from)[:len(*from)-1]
+	}
+	if len(*from) == 0 {
+		t.Notify("Nothing to "+name, true)
+		return
+	}
+
+	entry := (*from)[len(*from)-1]
+	*from = (*from)[:len(*from)-1]
+	*to = append(*to, current)
+
+	if err := t.DB.Restore(entry.snapshot); err != nil {
+		t.Notify(err.Error(), true)
+		return
+	}
+	t.refreshProjects()
+	t.ProjectPane.SelectByName(entry.projectName, entry.isView)
+
+	if entry.pane != nil {
+		if t.focusedPane() != entry.pane {
+			t.pushFocus(entry.pane.Box)
+		}
+		entry.pane.AdjustSelection()
+		if entry.cellText != "" {
+			entry.pane.SelectByText(entry.cellText)
+		}
+	}
+	t.Notify(name, false)
+}
@@ -2,19 +2,19 @@ package tui
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/1set/todotxt"
 	db "github.com/apxxxxxxe/kanban.txt/internal/db"
 	tsk "github.com/apxxxxxxe/kanban.txt/internal/task"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
-	"strings"
 )
 
 const (
 	defaultStatus = iota
-	todoDelete
-	doingDelete
-	doneDelete
 	taskArchive
 )
 
@@ -22,23 +22,22 @@ var ErrReferenceNotFound = errors.New("Reference not found")
 
 func (t *Tui) setKeybind() {
 	t.App.SetInputCapture(t.AppInputCaptureFunc)
-	t.DaysTable.SetInputCapture(t.daysTableInputCaptureFunc)
-	t.ProjectPane.SetInputCapture(t.projectPaneInputCaptureFunc)
-	t.TodoPane.SetInputCapture(t.todoPaneInputCaptureFunc)
-	t.DoingPane.SetInputCapture(t.doingPaneInputCaptureFunc)
-	t.DonePane.SetInputCapture(t.donePaneInputCaptureFunc)
 	t.InputWidget.SetInputCapture(t.inputWidgetInputCaptureFunc)
-	t.DescriptionWidget.SetInputCapture(t.descriptionWidgetInputCaptureFunc)
+	t.InputWidget.SetChangedFunc(t.searchChangedFunc)
+	t.KeymapWidget.SetInputCapture(t.keymapWidgetInputCaptureFunc)
+	t.StatsWidget.SetInputCapture(t.statsWidgetInputCaptureFunc)
+	t.ChartWidget.SetInputCapture(t.chartWidgetInputCaptureFunc)
+	t.ArchiveWidget.SetInputCapture(t.archiveWidgetInputCaptureFunc)
+	t.ColorWidget.SetInputCapture(t.colorWidgetInputCaptureFunc)
 }
 
 func (t *Tui) selectTask() (*todotxt.Task, string, error) {
 	var cell *tview.TableCell
-	if t.TodoPane.HasFocus() {
-		cell = t.TodoPane.GetCell(t.TodoPane.GetSelection())
-	} else if t.DoingPane.HasFocus() {
-		cell = t.DoingPane.GetCell(t.DoingPane.GetSelection())
-	} else if t.DonePane.HasFocus() {
-		cell = t.DonePane.GetCell(t.DonePane.GetSelection())
+	if pane := t.focusedPane(); pane != nil {
+		cell = pane.GetCell(pane.GetSelection())
+	}
+	if cell == nil {
+		return nil, "", ErrReferenceNotFound
 	}
 	cellText := cell.Text
 	task, err := getTaskFromCell(cell)
@@ -53,37 +52,119 @@ func getTaskFromCell(cell *tview.TableCell) (*todotxt.Task, error) {
 	return task, nil
 }
 
+// focusedScope returns the scope of the key bindings for the focused widget
+func (t *Tui) focusedScope() string {
+	switch {
+	case t.DaysTable.HasFocus():
+		return scopeDays
+	case t.ProjectPane.HasFocus():
+		return scopeProject
+	case t.focusedPane() != nil:
+		return scopeColumn
+	case t.DescriptionWidget.HasFocus():
+		return scopeDescription
+	}
+	return scopeGlobal
+}
+
+// AppInputCaptureFunc resolves the keys into actions of the keymap
+// and dispatches them to the focused widget.
 func (t *Tui) AppInputCaptureFunc(event *tcell.EventKey) *tcell.EventKey {
-	if t.InputWidget.HasFocus() {
+	if t.InputWidget.HasFocus() || t.ConflictWidget.HasFocus() || t.ConfirmWidget.HasFocus() || t.KeymapWidget.HasFocus() || t.StatsWidget.HasFocus() || t.ChartWidget.HasFocus() || t.ArchiveWidget.HasFocus() || t.ColorWidget.HasFocus() {
 		return event
 	}
 
-	switch event.Rune() {
-	case 'q':
+	scope := t.focusedScope()
+	scopes := []string{scope}
+	if t.Search.Filter != nil && (scope == scopeProject || isPaneScope(scope)) {
+		scopes = []string{scopeSearch, scope}
+	}
+	action, ok := t.Keymap.Match(event, scopes...)
+	if !ok {
+		return event
+	}
+	if action == "" {
+		t.Notify(t.Keymap.Pending()+"-", false)
+		return nil
+	}
+
+	// 他の操作を挟んだらアーカイブの確認をやり直す
+	if action != actArchiveTask {
+		t.ConfirmationStatus = defaultStatus
+	}
+
+	if event := t.globalAction(action, event); event == nil {
+		return nil
+	}
+	if event := t.searchAction(action, event); event == nil {
+		return nil
+	}
+
+	switch scope {
+	case scopeDays:
+		return t.daysTableAction(action, event)
+	case scopeProject:
+		return t.projectPaneAction(action, event)
+	case scopeColumn:
+		return t.paneAction(t.focusedColumn(), action, event)
+	case scopeDescription:
+		return t.descriptionWidgetAction(action, event)
+	}
+	return event
+}
+
+func (t *Tui) globalAction(action string, event *tcell.EventKey) *tcell.EventKey {
+	switch action {
+	case actQuit:
 		// Quit
-		t.App.Stop()
+		t.quit()
+		return nil
+	case actUndo:
+		t.undo()
+		return nil
+	case actRedo:
+		t.redo()
 		return nil
-	case 'p':
+	case actShowKeymap:
+		t.showKeymap()
+		return nil
+	case actShowStats:
+		t.showStats()
+		return nil
+	case actShowCharts:
+		t.showCharts()
+		return nil
+	case actShowArchive:
+		t.showArchive()
+		return nil
+	case actSearch:
+		t.startSearch()
+		return nil
+	case actNewProject:
 		t.InputWidget.SetTitle("New Project")
 		t.Pages.ShowPage(inputField)
 		t.pushFocus(t.InputWidget.Box)
 		t.InputWidget.Mode = 'p'
 		return nil
-	case 'n':
+	case actNewTask:
 		// New task
 		t.InputWidget.SetTitle("New Task")
 		t.Pages.ShowPage(inputField)
 		t.pushFocus(t.InputWidget.Box)
 		t.InputWidget.Mode = 'n'
 		return nil
-	case 'R':
+	case actRenameProject:
 		// Rename Current Project
+		if p := t.ProjectPane.GetCurrentProject(); p != nil && p.View != nil {
+			t.Notify("A view cannot be renamed; edit it in config.json", true)
+			return nil
+		}
 		t.InputWidget.SetTitle("Rename Project")
 		t.Pages.ShowPage(inputField)
 		t.pushFocus(t.InputWidget.Box)
 		t.InputWidget.Mode = 'R'
 		return nil
-	case 'a':
+	case actArchiveTask:
 		// Archive
 		if t.ConfirmationStatus == taskArchive {
 			task, _, err := t.selectTask()
@@ -91,16 +172,39 @@ func (t *Tui) AppInputCaptureFunc(event *tcell.EventKey) *tcell.EventKey {
 				t.Notify(err.Error(), true)
 				return nil
 			}
+			t.ConfirmationStatus = defaultStatus
+			if task == nil {
+				t.Notify("No task selected", true)
+				return nil
+			}
+			t.recordHistory()
 			t.DB.ArchiveTask(task)
 			t.refreshProjects()
-			t.Notify("Archived tasks", false)
-			t.ConfirmationStatus = defaultStatus
+			if pane := t.focusedPane(); pane != nil {
+				pane.AdjustSelection()
+			}
+			if _, ok := task.AdditionalTags[tsk.KeyRecID]; ok {
+				t.Notify("Archived the series of the task", false)
+			} else {
+				t.Notify("Archived the task", false)
+			}
 		} else {
 			t.ConfirmationStatus = taskArchive
-			t.Notify("Press a again to archive all tasks", false)
+			t.Notify("Press a again to archive the selected task", false)
 		}
 		return nil
-	case 'P':
+	case actArchiveDoneTasks:
+		if t.ProjectPane.GetCurrentProject() == nil {
+			t.Notify("No project selected", true)
+			return nil
+		}
+		t.InputWidget.SetTitle("Archive done tasks older than (days)")
+		t.InputWidget.SetText(fmt.Sprint(defaultArchiveDays))
+		t.Pages.ShowPage(inputField)
+		t.pushFocus(t.InputWidget.Box)
+		t.InputWidget.Mode = 'a'
+		return nil
+	case actCyclePriority:
 		// add or increment priority
 		task, cellText, err := t.selectTask()
 		if err != nil {
@@ -108,6 +212,7 @@ func (t *Tui) AppInputCaptureFunc(event *tcell.EventKey) *tcell.EventKey {
 			return nil
 		}
 		if task != nil {
+			t.recordHistory()
 			if task.Priority == "" {
 				task.Priority = priorityA
 			} else {
@@ -131,12 +236,8 @@ func (t *Tui) AppInputCaptureFunc(event *tcell.EventKey) *tcell.EventKey {
 			}
 
 			t.refreshProjects()
-			if t.TodoPane.HasFocus() {
-				t.TodoPane.SelectByText(cellText)
-			} else if t.DoingPane.HasFocus() {
-				t.DoingPane.SelectByText(cellText)
-			} else if t.DonePane.HasFocus() {
-				t.DonePane.SelectByText(cellText)
+			if pane := t.focusedPane(); pane != nil {
+				pane.SelectByText(cellText)
 			}
 		}
 		return nil
@@ -145,10 +246,27 @@ func (t *Tui) AppInputCaptureFunc(event *tcell.EventKey) *tcell.EventKey {
 	}
 }
 
-func (t *Tui) daysTableInputCaptureFunc(event *tcell.EventKey) *tcell.EventKey {
-	switch event.Rune() {
-	case 'j':
+func (t *Tui) searchAction(action string, event *tcell.EventKey) *tcell.EventKey {
+	switch action {
+	case actSearchNext:
+		t.jumpMatch(true)
+		return nil
+	case actSearchPrevious:
+		t.jumpMatch(false)
+		return nil
+	case actClearSearch:
+		t.setFilter(nil)
+		t.Notify("Search cleared", false)
+		return nil
+	}
+	return event
+}
+
+func (t *Tui) daysTableAction(action string, event *tcell.EventKey) *tcell.EventKey {
+	switch action {
+	case actFocusBoard:
 		t.popFocus()
+		return nil
 	}
 	return event
 }
@@ -161,221 +279,132 @@ func (t *Tui) moveToDaysTable(table *tview.Table) bool {
 	return false
 }
 
-func (t *Tui) projectPaneInputCaptureFunc(event *tcell.EventKey) *tcell.EventKey {
-	switch event.Rune() {
-	case 'k':
-		if t.moveToDaysTable(t.ProjectPane.Table) {
-			return nil
-		}
-	case 'l':
-		row, _ := t.TodoPane.GetSelection()
-		n := t.TodoPane.GetRowCount()
-		if row > n-1 {
-			t.TodoPane.Select(n-1, 0)
+// deleteTask deletes the selected task of pane after the chord of deleteTask
+func (t *Tui) deleteTask(pane *TodoTable, name string) {
+	if pane.GetRowCount() > 0 {
+		task, err := getTaskFromCell(pane.GetCell(pane.GetSelection()))
+		if err != nil {
+			panic(err)
 		}
-		t.pushFocus(t.TodoPane.Box)
-	}
-
-	return event
-}
 
-func (t *Tui) todoPaneInputCaptureFunc(event *tcell.EventKey) *tcell.EventKey {
-	f := func() {
-		// Move to DoingPane
-		project := t.ProjectPane.GetCurrentProject()
-		if t.TodoPane.GetRowCount() > 0 && project != nil {
-			ref, err := getTaskFromCell(t.TodoPane.GetCell(t.TodoPane.GetSelection()))
-			if err != nil {
-				panic(err)
-			}
-			tsk.ToDoing(ref, t.getSelectingDate())
-			t.refreshProjects()
+		t.recordHistory()
+		t.DB.LivingTasks.RemoveTask(task)
+		t.refreshProjects()
 
-			t.TodoPane.AdjustSelection()
-		}
-	}
-
-	switch event.Key() {
-	case tcell.KeyBackspace, tcell.KeyBackspace2:
-		f()
+		t.Notify("Deleted "+name+" task", false)
+	} else {
+		t.Notify("No "+name+" task here", true)
 	}
+}
 
-	switch event.Rune() {
-	case 'k':
-		if t.moveToDaysTable(t.TodoPane.Table) {
+func (t *Tui) projectPaneAction(action string, event *tcell.EventKey) *tcell.EventKey {
+	switch action {
+	case actFocusDays:
+		if t.moveToDaysTable(t.ProjectPane.Table) {
 			return nil
 		}
-	case 'd':
-		if t.ConfirmationStatus == todoDelete {
-			if t.TodoPane.GetRowCount() > 0 {
-				task, err := getTaskFromCell(t.TodoPane.GetCell(t.TodoPane.GetSelection()))
-				if err != nil {
-					panic(err)
-				}
-
-				t.DB.LivingTasks.RemoveTask(task)
-				t.refreshProjects()
-
-				t.Notify("deleted todo task", false)
-			} else {
-				t.Notify("No todo task here", true)
-			}
-			t.ConfirmationStatus = defaultStatus
-		} else {
-			t.ConfirmationStatus = todoDelete
-			t.Notify("Press d again to delete todo task", false)
-		}
-		return event
-	case 'h':
-		row, _ := t.ProjectPane.GetSelection()
-		n := t.ProjectPane.GetRowCount()
-		if row > n-1 {
-			t.ProjectPane.Select(n-1, 0)
-		}
-		t.pushFocus(t.ProjectPane.Box)
-	case 'l':
-		row, _ := t.DoingPane.GetSelection()
-		n := t.DoingPane.GetRowCount()
-		if row > n-1 {
-			t.DoingPane.Select(n-1, 0)
-		}
-		t.pushFocus(t.DoingPane.Box)
-	case 'J':
-		t.EditingCell = t.TodoPane.GetCell(t.TodoPane.GetSelection())
-		t.pushFocus(t.DescriptionWidget.Box)
-	case ' ':
-		f()
+	case actFocusRight:
+		t.focusPane(t.Panes[0].Table)
+		return nil
+	case actEditColor:
+		t.showColorPopup()
+		return nil
 	}
 
 	return event
 }
 
-func (t *Tui) doingPaneInputCaptureFunc(event *tcell.EventKey) *tcell.EventKey {
-	switch event.Key() {
-	case tcell.KeyBackspace, tcell.KeyBackspace2:
-		// Move to TodoPane
-		ref, err := getTaskFromCell(t.DoingPane.GetCell(t.DoingPane.GetSelection()))
-		if err != nil {
-			panic("doingPaneInputCaptureFunc: ref is not todotxt.Task")
-		}
-		tsk.ToTodo(ref)
-		t.refreshProjects()
-
-		t.DoingPane.AdjustSelection()
+// focusPane moves the focus to table keeping the selection in its rows
+func (t *Tui) focusPane(table *tview.Table) {
+	row, _ := table.GetSelection()
+	n := table.GetRowCount()
+	if row > n-1 {
+		table.Select(n-1, 0)
 	}
+	t.pushFocus(table.Box)
+}
 
-	switch event.Rune() {
-	case 'k':
-		if t.moveToDaysTable(t.DoingPane.Table) {
-			return nil
-		}
-	case 'd':
-		if t.ConfirmationStatus == doingDelete {
-			if t.DoingPane.GetRowCount() > 0 {
-				task, err := getTaskFromCell(t.DoingPane.GetCell(t.DoingPane.GetSelection()))
-				if err != nil {
-					panic(err)
-				}
-
-				t.DB.LivingTasks.RemoveTask(task)
-				t.refreshProjects()
-
-				t.Notify("Deleted doing task", false)
-			} else {
-				t.Notify("No doing task here", true)
-			}
-			t.ConfirmationStatus = defaultStatus
-		} else {
-			t.ConfirmationStatus = doingDelete
-			t.Notify("Press d again to delete doing task", false)
-		}
-		return event
-	case ' ':
-		// Move to DonePane
-		if t.DoingPane.GetRowCount() > 0 {
-			ref, err := getTaskFromCell(t.DoingPane.GetCell(t.DoingPane.GetSelection()))
-			if err != nil {
-				panic(err)
-			}
-			tsk.ToDone(ref, t.getSelectingDate())
-			t.refreshProjects()
-
-			t.DoingPane.AdjustSelection()
-		}
-	case 'h':
-		t.pushFocus(t.TodoPane.Box)
-	case 'l':
-		t.pushFocus(t.DonePane.Box)
-	case 'J':
-		t.EditingCell = t.DoingPane.GetCell(t.DoingPane.GetSelection())
-		t.pushFocus(t.DescriptionWidget.Box)
+// moveTask moves the selected task of the pane at index to the next (or previous) column.
+// The task at either end goes the other way.
+func (t *Tui) moveTask(index int, forward bool) {
+	pane := t.Panes[index]
+	if pane.GetRowCount() == 0 || t.ProjectPane.GetCurrentProject() == nil {
+		return
+	}
+	ref, err := getTaskFromCell(pane.GetCell(pane.GetSelection()))
+	if err != nil {
+		panic(err)
 	}
 
-	return event
-}
-
-func (t *Tui) donePaneInputCaptureFunc(event *tcell.EventKey) *tcell.EventKey {
-	f := func() {
-		// Move to DoingPane
-		if t.DonePane.GetRowCount() > 0 {
-			ref, err := getTaskFromCell(t.DonePane.GetCell(t.DonePane.GetSelection()))
-			if err != nil {
-				panic(err)
-			}
-			tsk.ToDoing(ref, t.getSelectingDate())
-			t.refreshProjects()
+	to := index - 1
+	if forward && index < len(t.Panes)-1 || index == 0 {
+		to = index + 1
+	}
+	if to < 0 || to >= len(t.Panes) {
+		return
+	}
 
-			t.DonePane.AdjustSelection()
+	if t.DB.IsWorkColumn(to) {
+		if blockers := t.DB.Blockers(ref); len(blockers) > 0 {
+			t.Notify("Blocked by "+blockersText(blockers), true)
+			return
 		}
 	}
+	limitErr := t.DB.CheckWIPLimit(ref, to)
+	if limitErr != nil && t.DB.GetColumns()[to].StrictLimit {
+		t.Notify(limitErr.Error(), true)
+		return
+	}
 
-	switch event.Key() {
-	case tcell.KeyBackspace, tcell.KeyBackspace2:
-		f()
+	if to == t.DB.DoneColumn() {
+		t.completeTask(ref)
+	} else {
+		t.recordHistory()
+		t.DB.MoveTask(ref, to, t.getSelectingDate())
+		t.refreshProjects()
+		pane.AdjustSelection()
+	}
+	if limitErr != nil {
+		t.Notify(limitErr.Error(), true)
 	}
+}
+
+func (t *Tui) paneAction(index int, action string, event *tcell.EventKey) *tcell.EventKey {
+	pane := t.Panes[index]
 
-	switch event.Rune() {
-	case 'k':
-		if t.moveToDaysTable(t.DonePane.Table) {
+	switch action {
+	case actFocusDays:
+		if t.moveToDaysTable(pane.Table) {
 			return nil
 		}
-	case 'd':
-		if t.ConfirmationStatus == doneDelete {
-			if t.DonePane.GetRowCount() > 0 {
-				task, err := getTaskFromCell(t.DonePane.GetCell(t.DonePane.GetSelection()))
-				if err != nil {
-					panic(err)
-				}
-
-				t.DB.LivingTasks.RemoveTask(task)
-				t.refreshProjects()
-
-				t.Notify("Deleted done task", false)
-			} else {
-				t.Notify("No done task here", true)
-			}
-			t.ConfirmationStatus = defaultStatus
+		return event
+	case actDeleteTask:
+		t.deleteTask(pane, strings.ToLower(t.DB.GetColumns()[index].Name))
+	case actFocusLeft:
+		if index == 0 {
+			t.focusPane(t.ProjectPane.Table)
 		} else {
-			t.ConfirmationStatus = doneDelete
-			t.Notify("Press d again to delete done task", false)
+			t.focusPane(t.Panes[index-1].Table)
 		}
-		return event
-	case ' ':
-		f()
-		// or Move to Archive
-	case 'h':
-		row, _ := t.DoingPane.GetSelection()
-		n := t.DoingPane.GetRowCount()
-		if row > n-1 {
-			t.DoingPane.Select(n-1, 0)
+	case actFocusRight:
+		if index == len(t.Panes)-1 {
+			return event
 		}
-		t.pushFocus(t.DoingPane.Box)
-	case 'J':
-		t.EditingCell = t.DonePane.GetCell(t.DonePane.GetSelection())
+		t.focusPane(t.Panes[index+1].Table)
+	case actEditTask:
+		t.EditingCell = pane.GetCell(pane.GetSelection())
 		t.pushFocus(t.DescriptionWidget.Box)
+	case actAddSubtask:
+		t.startAddSubtask(pane)
+	case actMoveTaskForward:
+		t.moveTask(index, true)
+	case actMoveTaskBackward:
+		t.moveTask(index, false)
+	default:
+		return event
 	}
 
-	return event
+	return nil
 }
 
 func (t *Tui) inputWidgetInputCaptureFunc(event *tcell.EventKey) *tcell.EventKey {
@@ -388,19 +417,22 @@ func (t *Tui) inputWidgetInputCaptureFunc(event *tcell.EventKey) *tcell.EventKey
 		// it prevents the focus from the effect of HidePage
 		t.App.SetFocus(focus)
 
-		t.InputWidget.SetText("")
 		t.InputWidget.Mode = ' '
+		t.InputWidget.SetText("")
 	}
 
 	switch event.Key() {
 	case tcell.KeyEscape:
 		t.Pages.HidePage(inputField)
 		t.popFocus()
-		t.InputWidget.SetText("")
 		switch t.InputWidget.Mode {
 		case 'f':
 			t.popFocus()
+		case '/':
+			t.InputWidget.Mode = ' '
+			t.cancelSearch()
 		}
+		t.InputWidget.SetText("")
 		t.InputWidget.Mode = ' '
 		return nil
 	case tcell.KeyEnter:
@@ -408,55 +440,80 @@ func (t *Tui) inputWidgetInputCaptureFunc(event *tcell.EventKey) *tcell.EventKey
 		switch t.InputWidget.Mode {
 		case 'n':
 			// New Task
-			taskFields := []string{}
-			for _, field := range strings.Split(input, " ") {
-				if field == "" {
-					continue
-				}
-				taskFields = append(taskFields, tsk.ReplaceInvalidTag(field)+" ")
+			projectName := db.NoProject
+			if project != nil && project.ProjectName != db.AllTasks && project.View == nil {
+				projectName = project.ProjectName
 			}
-			task, err := todotxt.ParseTask(strings.Join(taskFields, " "))
+			task, err := db.NewTask(input, projectName, t.getSelectingDate())
 			if err != nil {
 				t.Notify(err.Error(), true)
 				return nil
 			}
 
-			// add CreatedDate
-			task.CreatedDate = t.getSelectingDate()
-
-			// add current project
-			if project == nil || project.ProjectName == db.AllTasks {
-				task.Projects = []string{db.NoProject}
-			} else {
-				task.Projects = []string{project.ProjectName}
-			}
-
-			// remove context "doing"
-			for i, context := range task.Contexts {
-				if context == "doing" {
-					task.Contexts = append(task.Contexts[:i], task.Contexts[i+1:]...)
-					break
-				}
-			}
-
-			t.DB.LivingTasks.AddTask(task)
+			t.recordHistory()
+			t.DB.AddTask(task)
 			t.refreshProjects()
 
 		case 'p':
 			// New Project
 			day, _ := t.getCurrentDay()
-			t.DB.RefreshProjects(day)
+			if err := t.DB.BuildProjects(day); err != nil {
+				t.Notify(err.Error(), true)
+			}
 			t.DB.Projects = append(t.DB.Projects, &db.Project{ProjectName: input})
 			t.ProjectPane.ResetCell(t.DB.Projects)
 
 		case 'R':
 			// Rename Project
+			t.recordHistory()
 			taskList := t.DB.LivingTasks.Filter(todotxt.FilterByProject(project.ProjectName))
 			for _, task := range *taskList {
-				task.Projects = []string{input}
+				// keep the other projects of the task
+				for i, p := range task.Projects {
+					if p == project.ProjectName {
+						task.Projects[i] = input
+					}
+				}
+				task.Projects = tsk.GetProjectNames(*task)
+			}
+			t.refreshProjects()
+
+		case 's':
+			// New Subtask
+			parent, err := getTaskFromCell(t.EditingCell)
+			if err != nil {
+				t.Notify(err.Error(), true)
+				return nil
 			}
+			task, err := db.NewTask(input, "", t.getSelectingDate())
+			if err != nil {
+				t.Notify(err.Error(), true)
+				return nil
+			}
+
+			t.recordHistory()
+			t.DB.AddSubtask(parent, task)
 			t.refreshProjects()
 
+		case 'a':
+			// Archive Done Tasks
+			days, err := strconv.Atoi(strings.TrimSpace(input))
+			if err != nil || days < 0 {
+				t.Notify("the days must be a non-negative number", true)
+				return nil
+			}
+			t.recordHistory()
+			count := t.DB.ArchiveDoneTasks(project, t.getSelectingDate().AddDate(0, 0, -days))
+			t.refreshProjects()
+			t.Notify(fmt.Sprintf("Archived %d done tasks", count), false)
+
+		case '/':
+			// Search
+			if err := t.commitSearch(input); err != nil {
+				t.Notify(err.Error(), true)
+				return nil
+			}
+
 		case 'f':
 			// Edit Field
 			field := t.InputWidget.GetTitle()
@@ -465,22 +522,32 @@ func (t *Tui) inputWidgetInputCaptureFunc(event *tcell.EventKey) *tcell.EventKey
 			if err != nil {
 				panic(err)
 			}
-			setTaskField(task, field, input)
+			// 変更前の状態を取っておき、入力が受け入れられたときだけ履歴に積む
+			entry := t.newHistoryEntry()
+			old := getTaskField(t.DB, task, field)
+			if err := setTaskField(t.DB, task, field, input); err != nil {
+				// keep the input field open to fix the value
+				t.Notify(err.Error(), true)
+				return nil
+			}
+			if field == todoDependencies {
+				if err := t.DB.CheckDependencies(task); err != nil {
+					setTaskField(t.DB, task, field, old)
+					t.Notify(err.Error(), true)
+					return nil
+				}
+			}
+			t.pushHistory(entry)
 
 			t.popFocus() // pop focus from inputWidget
 			t.popFocus() // pop focus from descriptionWidget
 			// now focus is on the pane
 
-			var selectCell func(string)
-			if t.TodoPane.HasFocus() {
-				selectCell = t.TodoPane.SelectByText
-			} else if t.DoingPane.HasFocus() {
-				selectCell = t.DoingPane.SelectByText
-			} else if t.DonePane.HasFocus() {
-				selectCell = t.DonePane.SelectByText
-			} else {
+			pane := t.focusedPane()
+			if pane == nil {
 				panic("inputWidgetInputCaptureFunc: no pane has focus")
 			}
+			selectCell := pane.SelectByText
 
 			t.refreshProjects()
 			hideInputField()
@@ -496,31 +563,107 @@ func (t *Tui) inputWidgetInputCaptureFunc(event *tcell.EventKey) *tcell.EventKey
 	return event
 }
 
-func (t *Tui) descriptionWidgetInputCaptureFunc(event *tcell.EventKey) *tcell.EventKey {
+func (t *Tui) descriptionWidgetAction(action string, event *tcell.EventKey) *tcell.EventKey {
 	f := func() {
 		row, _ := t.DescriptionWidget.GetSelection()
-		field := t.DescriptionWidget.GetCell(row, 0).GetReference().(string)
-		t.InputWidget.SetTitle(field)
+		field, ok := t.DescriptionWidget.GetCell(row, 0).GetReference().(string)
+		if !ok {
+			// the pane had no task to describe
+			t.Notify("No task selected", true)
+			return
+		}
 		task, ok := t.EditingCell.GetReference().(*todotxt.Task)
 		if !ok {
-			panic("descriptionWidgetInputCaptureFunc: ref is not *todotxt.Task")
+			t.Notify("No task selected", true)
+			return
+		}
+		if field == todoID && getTaskField(t.DB, task, field) == "" {
+			t.assignID()
+			return
+		}
+		if isReadOnlyField(field) {
+			t.Notify(field+" is read-only", true)
+			return
 		}
-		t.InputWidget.SetText(getTaskField(task, field))
+		if field == todoNote && strings.Contains(getTaskField(t.DB, task, field), "\n") {
+			// 入力欄は1行なので複数行の備考はエディタで編集する
+			t.editInEditor(false)
+			return
+		}
+		t.InputWidget.SetTitle(field)
+		t.InputWidget.SetText(getTaskField(t.DB, task, field))
 		t.InputWidget.Mode = 'f'
 		t.Pages.ShowPage(inputField)
 		t.pushFocus(t.InputWidget.Box)
 	}
 
-	switch event.Key() {
-	case tcell.KeyEnter:
+	switch action {
+	case actEditField:
 		f()
-	}
-	switch event.Rune() {
-	case 'K':
+	case actCloseDescription:
 		t.popFocus()
-	case ' ':
-		f()
+	case actSkipOccurrence:
+		t.skipNextOccurrence()
+	case actEditNote:
+		t.editInEditor(false)
+	case actEditLine:
+		t.editInEditor(true)
+	default:
+		return event
 	}
 
-	return event
+	return nil
+}
+
+// assignID gives the task in the description an id to be referred by dep: and parent:
+func (t *Tui) assignID() {
+	task, err := getTaskFromCell(t.EditingCell)
+	if err != nil {
+		t.Notify(err.Error(), true)
+		return
+	}
+	pane := t.editingPane()
+	cellText := t.EditingCell.Text
+
+	t.recordHistory()
+	id := t.DB.EnsureID(task)
+	t.refreshProjects()
+
+	// the cells are recreated by refreshProjects
+	if pane != nil {
+		pane.SelectByText(cellText)
+		t.EditingCell = pane.GetCell(pane.GetSelection())
+	}
+	t.Notify("Assigned id:"+id, false)
+}
+
+func (t *Tui) skipNextOccurrence() {
+	task, err := getTaskFromCell(t.EditingCell)
+	if err != nil {
+		t.Notify(err.Error(), true)
+		return
+	}
+	if _, ok := task.AdditionalTags[tsk.KeyRec]; !ok {
+		t.Notify("Not a recurring task", true)
+		return
+	}
+	pane := t.editingPane()
+	cellText := t.EditingCell.Text
+
+	// 失敗したときに何も戻さない履歴を積まないよう、成功してから記録する
+	entry := t.newHistoryEntry()
+	next, err := tsk.SkipNextOccurrence(task)
+	if err != nil {
+		t.Notify(err.Error(), true)
+		return
+	}
+	t.pushHistory(entry)
+	t.refreshProjects()
+
+	// the cells are recreated by refreshProjects
+	if pane != nil {
+		pane.SelectByText(cellText)
+		t.EditingCell = pane.GetCell(pane.GetSelection())
+	}
+	t.Notify("Skipped the occurrence on "+timeToStr(next), false)
 }
@@ -0,0 +1,465 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// scopes of key bindings
+const (
+	scopeGlobal      = "Global"
+	scopeDays        = "Days"
+	scopeProject     = projectPaneTitle
+	scopeColumn      = "Column" // the panes of the columns
+	scopeDescription = descriptionWidgetTitle
+	// bindings in the panes while a search filter is applied, which shadow the others
+	scopeSearch = searchWidgetTitle
+)
+
+// names of actions used as the keys of the "keys" section in config.json
+const (
+	actQuit             = "quit"
+	actNewProject       = "newProject"
+	actNewTask          = "newTask"
+	actRenameProject    = "renameProject"
+	actArchiveTask      = "archiveTask"
+	actCyclePriority    = "cyclePriority"
+	actUndo             = "undo"
+	actRedo             = "redo"
+	actShowKeymap       = "showKeymap"
+	actFocusDays        = "focusDays"
+	actFocusBoard       = "focusBoard"
+	actFocusLeft        = "focusLeft"
+	actFocusRight       = "focusRight"
+	actDeleteTask       = "deleteTask"
+	actMoveTaskForward  = "moveTaskForward"
+	actMoveTaskBackward = "moveTaskBackward"
+	actEditTask         = "editTask"
+	actEditField        = "editField"
+	actCloseDescription = "closeDescription"
+	actSkipOccurrence   = "skipOccurrence"
+	actEditColor        = "editColor"
+	actSearch           = "search"
+	actSearchNext       = "searchNext"
+	actSearchPrevious   = "searchPrevious"
+	actClearSearch      = "clearSearch"
+	actAddSubtask       = "addSubtask"
+	actShowStats        = "showStats"
+	actShowCharts       = "showCharts"
+	actShowArchive      = "showArchive"
+	actArchiveDoneTasks = "archiveDoneTasks"
+	actEditNote         = "editNote"
+	actEditLine         = "editLine"
+)
+
+type keyAction struct {
+	name        string
+	description string
+	scopes      []string
+	defaults    []string
+}
+
+// keymapScopes is in the order shown in the keymap page
+var keymapScopes = []string{scopeGlobal, scopeDays, scopeProject, scopeColumn, scopeDescription, scopeSearch}
+
+var paneScopes = []string{scopeColumn}
+
+func isPaneScope(scope string) bool {
+	for _, s := range paneScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+var keyActions = []keyAction{
+	{actQuit, "quit", []string{scopeGlobal}, []string{"q"}},
+	{actNewProject, "add a new project", []string{scopeGlobal}, []string{"p"}},
+	{actNewTask, "add a new task", []string{scopeGlobal}, []string{"n"}},
+	{actRenameProject, "rename the current project", []string{scopeGlobal}, []string{"R"}},
+	{actArchiveTask, "archive the selected task, or its series if it recurs (press twice)", []string{scopeGlobal}, []string{"a"}},
+	{actCyclePriority, "cycle the priority of the selected task", []string{scopeGlobal}, []string{"P"}},
+	{actUndo, "undo", []string{scopeGlobal}, []string{"u"}},
+	{actRedo, "redo", []string{scopeGlobal}, []string{"<Ctrl-r>"}},
+	{actShowKeymap, "show this keymap", []string{scopeGlobal}, []string{"?"}},
+	{actShowStats, "show the flow metrics of the current project", []string{scopeGlobal}, []string{"M"}},
+	{actShowCharts, "show the burndown and the cumulative flow of the current project", []string{scopeGlobal}, []string{"C"}},
+	{actArchiveDoneTasks, "archive the done tasks of the current project older than some days", []string{scopeGlobal}, []string{"<Ctrl-a>"}},
+	{actShowArchive, "show the archived tasks", []string{scopeGlobal}, []string{"A"}},
+	{actSearch, "filter the tasks by a substring or a /regexp/", []string{scopeGlobal}, []string{"/"}},
+	{actSearchNext, "select the next matching task", []string{scopeSearch}, []string{"n"}},
+	{actSearchPrevious, "select the previous matching task", []string{scopeSearch}, []string{"N"}},
+	{actClearSearch, "clear the search filter", []string{scopeSearch}, []string{"<Esc>"}},
+	{actFocusDays, "move to the days bar from the top row", []string{scopeProject, scopeColumn}, []string{"k"}},
+	{actFocusBoard, "go back to the board", []string{scopeDays}, []string{"j"}},
+	{actFocusLeft, "move to the left pane", paneScopes, []string{"h"}},
+	{actFocusRight, "move to the right pane", []string{scopeProject, scopeColumn}, []string{"l"}},
+	{actDeleteTask, "delete the selected task", paneScopes, []string{"dd"}},
+	{actMoveTaskForward, "move the selected task to the next column", paneScopes, []string{"<Space>"}},
+	{actMoveTaskBackward, "move the selected task to the previous column", paneScopes, []string{"<Backspace>"}},
+	{actEditColor, "choose the color of the current project", []string{scopeProject}, []string{"c"}},
+	{actEditTask, "edit the selected task in the description", paneScopes, []string{"J"}},
+	{actAddSubtask, "add a subtask to the selected task", paneScopes, []string{"S"}},
+	{actEditField, "edit the selected field", []string{scopeDescription}, []string{"<Enter>", "<Space>"}},
+	{actCloseDescription, "go back to the pane", []string{scopeDescription}, []string{"K"}},
+	{actSkipOccurrence, "skip the next occurrence of the recurring task", []string{scopeDescription}, []string{"s"}},
+	{actEditNote, "edit the note in $EDITOR", []string{scopeDescription}, []string{"e"}},
+	{actEditLine, "edit the whole line of the task in $EDITOR", []string{scopeDescription}, []string{"E"}},
+}
+
+type keyBinding struct {
+	action   string
+	sequence []string
+}
+
+func (b keyBinding) String() string {
+	return strings.Join(b.sequence, "")
+}
+
+// Keymap resolves key sequences, including multi-key chords, into actions.
+type Keymap struct {
+	bindings map[string][]keyBinding
+	pending  []string
+	scope    string
+}
+
+// NewKeymap builds the keymap from the default bindings overridden by keys,
+// which maps action names to key sequences such as "dd" or "<Ctrl-r>".
+func NewKeymap(keys map[string][]string) (*Keymap, error) {
+	errs := []string{}
+
+	known := map[string]bool{}
+	for _, a := range keyActions {
+		known[a.name] = true
+	}
+	for name := range keys {
+		if !known[name] {
+			errs = append(errs, "unknown action: "+name)
+		}
+	}
+
+	k := &Keymap{bindings: map[string][]keyBinding{}}
+	for _, a := range keyActions {
+		sequences := a.defaults
+		if s, ok := keys[a.name]; ok {
+			sequences = s
+		}
+		for _, s := range sequences {
+			seq, err := parseKeySequence(s)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %s", a.name, err))
+				continue
+			}
+			for _, scope := range a.scopes {
+				k.bindings[scope] = append(k.bindings[scope], keyBinding{action: a.name, sequence: seq})
+			}
+		}
+	}
+
+	errs = append(errs, k.conflicts()...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid key bindings:\n  %s", strings.Join(errs, "\n  "))
+	}
+	return k, nil
+}
+
+// conflicts reports the bindings which shadow each other in a scope
+func (k *Keymap) conflicts() []string {
+	errs := []string{}
+	seen := map[string]bool{}
+	for _, scope := range keymapScopes {
+		bindings := k.scopeBindings(scope)
+		if scope == scopeSearch {
+			bindings = k.bindings[scopeSearch]
+		}
+		for i, a := range bindings {
+			for _, b := range bindings[i+1:] {
+				if a.action == b.action || !(hasPrefix(a.sequence, b.sequence) || hasPrefix(b.sequence, a.sequence)) {
+					continue
+				}
+				msg := fmt.Sprintf("%s conflicts with %s (%s, %s)", a, b, a.action, b.action)
+				if !seen[msg] {
+					errs = append(errs, msg)
+					seen[msg] = true
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// scopeBindings returns the bindings of scopes followed by the global ones, in the order of precedence
+func (k *Keymap) scopeBindings(scopes ...string) []keyBinding {
+	bindings := []keyBinding{}
+	for _, scope := range scopes {
+		if scope != scopeGlobal {
+			bindings = append(bindings, k.bindings[scope]...)
+		}
+	}
+	return append(bindings, k.bindings[scopeGlobal]...)
+}
+
+// Match feeds event to the keymap looking up the bindings of scopes in order.
+// It returns the action bound to the keys typed so far,
+// or an empty action with true while waiting for the next key of a chord.
+func (k *Keymap) Match(event *tcell.EventKey, scopes ...string) (string, bool) {
+	scope := strings.Join(scopes, " ")
+	if scope != k.scope {
+		k.pending = nil
+		k.scope = scope
+	}
+	k.pending = append(k.pending, eventKeyName(event))
+
+	waiting := false
+	for _, b := range k.scopeBindings(scopes...) {
+		if equalSequence(b.sequence, k.pending) {
+			k.pending = nil
+			return b.action, true
+		}
+		if hasPrefix(b.sequence, k.pending) {
+			waiting = true
+		}
+	}
+	if waiting {
+		return "", true
+	}
+
+	if len(k.pending) > 1 {
+		// the key may start another sequence
+		k.pending = nil
+		return k.Match(event, scopes...)
+	}
+	k.pending = nil
+	return "", false
+}
+
+// Pending returns the keys of the chord being typed.
+func (k *Keymap) Pending() string {
+	return strings.Join(k.pending, "")
+}
+
+// Bindings returns the effective key sequences of each action in scope.
+func (k *Keymap) Bindings(scope string) [][]string {
+	lines := [][]string{}
+	for _, a := range keyActions {
+		seqs := []string{}
+		for _, b := range k.bindings[scope] {
+			if b.action == a.name {
+				seqs = append(seqs, b.String())
+			}
+		}
+		if len(seqs) > 0 {
+			lines = append(lines, []string{strings.Join(seqs, " "), a.name, a.description})
+		}
+	}
+	return lines
+}
+
+func hasPrefix(seq, prefix []string) bool {
+	return len(prefix) <= len(seq) && equalSequence(seq[:len(prefix)], prefix)
+}
+
+func equalSequence(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// eventKeyName returns the name of the key in the notation of parseKeySequence
+func eventKeyName(event *tcell.EventKey) string {
+	key := event.Key()
+	mod := event.Modifiers()
+
+	switch {
+	case key == tcell.KeyRune && event.Rune() == ' ':
+		return "<Space>"
+	case key == tcell.KeyRune && mod&tcell.ModAlt != 0:
+		return "<Alt-" + string(event.Rune()) + ">"
+	case key == tcell.KeyRune:
+		return string(event.Rune())
+	case key == tcell.KeyBackspace || key == tcell.KeyBackspace2:
+		return "<Backspace>"
+	case key >= tcell.KeyCtrlA && key <= tcell.KeyCtrlZ && key != tcell.KeyTab && key != tcell.KeyEnter:
+		return "<Ctrl-" + string(rune('a'+key-tcell.KeyCtrlA)) + ">"
+	}
+
+	name, ok := tcell.KeyNames[key]
+	if !ok {
+		return event.Name()
+	}
+	prefix := ""
+	if mod&tcell.ModCtrl != 0 {
+		prefix += "Ctrl-"
+	}
+	if mod&tcell.ModAlt != 0 {
+		prefix += "Alt-"
+	}
+	if mod&tcell.ModShift != 0 {
+		prefix += "Shift-"
+	}
+	return "<" + prefix + name + ">"
+}
+
+var keyAliases = map[string]string{
+	"space":  "Space",
+	"bs":     "Backspace",
+	"cr":     "Enter",
+	"return": "Enter",
+	"escape": "Esc",
+}
+
+// parseKeySequence parses a key sequence in a vim-like notation:
+// characters stand for themselves and special keys are written in angle brackets,
+// e.g. "dd", "<Ctrl-r>", "<Alt-x>", "<Space>", "<Enter>", "<lt>".
+func parseKeySequence(s string) ([]string, error) {
+	if s == "" {
+		return nil, fmt.Errorf("empty key sequence")
+	}
+
+	seq := []string{}
+	for len(s) > 0 {
+		if s[0] == '<' {
+			end := strings.Index(s, ">")
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed key name: %s", s)
+			}
+			name, err := parseKeyName(s[1:end])
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, name)
+			s = s[end+1:]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s)
+		if r == ' ' {
+			seq = append(seq, "<Space>")
+		} else {
+			seq = append(seq, string(r))
+		}
+		s = s[size:]
+	}
+	return seq, nil
+}
+
+func parseKeyName(s string) (string, error) {
+	parts := strings.Split(s, "-")
+	if strings.HasSuffix(s, "--") {
+		// <Ctrl--> or <Alt-->
+		parts = append(parts[:len(parts)-2], "-")
+	}
+	name := parts[len(parts)-1]
+	ctrl, alt, shift := false, false, false
+	for _, m := range parts[:len(parts)-1] {
+		switch strings.ToLower(m) {
+		case "c", "ctrl":
+			ctrl = true
+		case "a", "m", "alt", "meta":
+			alt = true
+		case "s", "shift":
+			shift = true
+		default:
+			return "", fmt.Errorf("unknown modifier %q in <%s>", m, s)
+		}
+	}
+
+	if strings.ToLower(name) == "lt" {
+		name = "<"
+	}
+
+	if utf8.RuneCountInString(name) == 1 {
+		r, _ := utf8.DecodeRuneInString(name)
+		switch {
+		case ctrl && !alt && !shift && r >= 'a' && r <= 'z' || ctrl && r >= 'A' && r <= 'Z':
+			return "<Ctrl-" + strings.ToLower(name) + ">", nil
+		case alt && !ctrl:
+			if shift {
+				name = strings.ToUpper(name)
+			}
+			return "<Alt-" + name + ">", nil
+		case !ctrl && !alt:
+			if shift {
+				name = strings.ToUpper(name)
+			}
+			if name == " " {
+				return "<Space>", nil
+			}
+			return name, nil
+		}
+		return "", fmt.Errorf("unsupported key <%s>", s)
+	}
+
+	if alias, ok := keyAliases[strings.ToLower(name)]; ok {
+		name = alias
+	} else {
+		found := false
+		for _, n := range tcell.KeyNames {
+			if strings.EqualFold(n, name) && n != "Backspace2" {
+				name = n
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("unknown key <%s>", s)
+		}
+	}
+	if strings.HasPrefix(name, "Ctrl-") {
+		return "<Ctrl-" + strings.ToLower(name[len("Ctrl-"):]) + ">", nil
+	}
+	if name == "Space" || name == "Backspace" {
+		// modifiers of these keys are not distinguished
+		return "<" + name + ">", nil
+	}
+
+	prefix := ""
+	if ctrl {
+		prefix += "Ctrl-"
+	}
+	if alt {
+		prefix += "Alt-"
+	}
+	if shift {
+		prefix += "Shift-"
+	}
+	return "<" + prefix + name + ">", nil
+}
+
+func (t *Tui) showKeymap() {
+	text := ""
+	for _, scope := range keymapScopes {
+		bindings := t.Keymap.Bindings(scope)
+		if len(bindings) == 0 {
+			continue
+		}
+		text += "[#a0a0a0::b]" + scope + "[-::-]\n"
+		for _, b := range bindings {
+			text += fmt.Sprintf("  %-16s %-18s %s\n", tview.Escape(b[0]), b[1], b[2])
+		}
+		text += "\n"
+	}
+	text += "Press Esc or q to close"
+	t.KeymapWidget.SetText(text).ScrollToBeginning()
+	t.Pages.ShowPage(keymapPage)
+	t.pushFocus(t.KeymapWidget.Box)
+}
+
+func (t *Tui) keymapWidgetInputCaptureFunc(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() == tcell.KeyEscape || event.Rune() == 'q' {
+		t.Pages.HidePage(keymapPage)
+		t.popFocus()
+		return nil
+	}
+	return event
+}
@@ -0,0 +1,136 @@
+package tui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParseKeySequence(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"d", []string{"d"}},
+		{"dd", []string{"d", "d"}},
+		{"gT", []string{"g", "T"}},
+		{" ", []string{"<Space>"}},
+		{"<Space>", []string{"<Space>"}},
+		{"<space>", []string{"<Space>"}},
+		{"<Ctrl-r>", []string{"<Ctrl-r>"}},
+		{"<C-R>", []string{"<Ctrl-r>"}},
+		{"<c-r>", []string{"<Ctrl-r>"}},
+		{"<Alt-x>", []string{"<Alt-x>"}},
+		{"<M-x>", []string{"<Alt-x>"}},
+		{"<S-a>", []string{"A"}},
+		{"<lt>", []string{"<"}},
+		{"<cr>", []string{"<Enter>"}},
+		{"<Enter>", []string{"<Enter>"}},
+		{"<Esc>", []string{"<Esc>"}},
+		{"<escape>", []string{"<Esc>"}},
+		{"<bs>", []string{"<Backspace>"}},
+		{"<Up>", []string{"<Up>"}},
+		{"<S-Up>", []string{"<Shift-Up>"}},
+		{"g<Tab>", []string{"g", "<Tab>"}},
+		{"<Ctrl-w>h", []string{"<Ctrl-w>", "h"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseKeySequence(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseKeySequenceInvalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"<Ctrl-r",
+		"<Hyper-x>",
+		"<Nope>",
+		"<Ctrl-Alt-x>",
+		"<Ctrl-1>",
+	} {
+		t.Run(in, func(t *testing.T) {
+			if got, err := parseKeySequence(in); err == nil {
+				t.Errorf("got %q, want an error", got)
+			}
+		})
+	}
+}
+
+func TestNewKeymap(t *testing.T) {
+	tests := []struct {
+		name string
+		keys map[string][]string
+		// a substring of the error, empty for no error
+		wantErr string
+	}{
+		{"defaults", nil, ""},
+		{"rebound", map[string][]string{actRedo: {"<C-y>"}, actDeleteTask: {"x", "dd"}}, ""},
+		{"unbound", map[string][]string{actQuit: {}}, ""},
+		{"same key in other scopes", map[string][]string{actSkipOccurrence: {"c"}}, ""},
+		{"search bindings shadow the others", map[string][]string{actSearchNext: {"q"}}, ""},
+		{"unknown action", map[string][]string{"fly": {"f"}}, "unknown action: fly"},
+		{"invalid chord", map[string][]string{actQuit: {"<Nope>"}}, "quit: unknown key"},
+		{"same key in a scope", map[string][]string{actEditLine: {"e"}}, "conflicts with"},
+		{"prefix of a chord", map[string][]string{actEditTask: {"d"}}, "conflicts with"},
+		{"global key in a scope", map[string][]string{actEditNote: {"q"}}, "conflicts with"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeymap(tt.keys)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeymapMatch(t *testing.T) {
+	k, err := NewKeymap(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type step struct {
+		key    rune
+		action string
+		ok     bool
+	}
+	tests := []struct {
+		name   string
+		scopes []string
+		steps  []step
+	}{
+		{"single key", []string{scopeColumn}, []step{{'J', actEditTask, true}}},
+		{"global key", []string{scopeColumn}, []step{{'q', actQuit, true}}},
+		{"chord", []string{scopeColumn}, []step{{'d', "", true}, {'d', actDeleteTask, true}}},
+		{"broken chord starts again", []string{scopeColumn}, []step{{'d', "", true}, {'J', actEditTask, true}}},
+		{"unbound key", []string{scopeColumn}, []step{{'z', "", false}}},
+		{"search shadows the global key", []string{scopeSearch, scopeColumn}, []step{{'n', actSearchNext, true}}},
+		{"out of scope", []string{scopeDays}, []step{{'J', "", false}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, s := range tt.steps {
+				action, ok := k.Match(tcell.NewEventKey(tcell.KeyRune, s.key, tcell.ModNone), tt.scopes...)
+				if action != s.action || ok != s.ok {
+					t.Errorf("step %d %q: got %q, %v, want %q, %v", i, s.key, action, ok, s.action, s.ok)
+				}
+			}
+		})
+	}
+}
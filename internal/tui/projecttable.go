@@ -8,6 +8,7 @@ import (
 
 type ProjectTable struct {
 	*tview.Table
+	Color *db.ColorConfig
 }
 
 func (t *ProjectTable) GetCurrentProject() *db.Project {
@@ -18,6 +19,17 @@ func (t *ProjectTable) GetCurrentProject() *db.Project {
 	return p
 }
 
+// SelectByName selects the project, or the view if isView, named name
+func (t *ProjectTable) SelectByName(name string, isView bool) {
+	for row := 0; row < t.GetRowCount(); row++ {
+		p, ok := t.GetCell(row, 0).GetReference().(*db.Project)
+		if ok && p.ProjectName == name && (p.View != nil) == isView {
+			t.Select(row, 0)
+			return
+		}
+	}
+}
+
 func (t *ProjectTable) ResetCell(projects []*db.Project) {
 	t.Clear()
 	for _, project := range projects {
@@ -32,17 +44,23 @@ func (t *ProjectTable) setCell(p *db.Project) *tview.TableCell {
 		cell := t.GetCell(i, 0)
 		ref, ok := cell.GetReference().(*db.Project)
 		if ok {
-			if ref.ProjectName == p.ProjectName {
+			if ref.ProjectName == p.ProjectName && (ref.View == nil) == (p.View == nil) {
 				targetRow = i
 				break
 			}
 		}
 	}
 
-	cell := tview.NewTableCell(p.ProjectName).SetReference(p)
+	text := tview.Escape(p.ProjectName)
+	if p.View != nil {
+		text = "󰈲 " + text
+	}
+	cell := tview.NewTableCell(text).SetReference(p)
 
-	if len(p.TodoTasks)+len(p.DoingTasks) == 0 {
+	if p.OpenTaskCount() == 0 {
 		cell.SetTextColor(tcell.ColorGray)
+	} else if isPaintable(t.Color, p.ProjectName) {
+		cell.SetTextColor(projectColor(t.Color, p.ProjectName))
 	}
 
 	t.SetCell(targetRow, 0, cell)
@@ -0,0 +1,176 @@
+package tui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/1set/todotxt"
+	"github.com/rivo/tview"
+
+	db "github.com/apxxxxxxe/kanban.txt/internal/db"
+	tsk "github.com/apxxxxxxe/kanban.txt/internal/task"
+)
+
+const searchWidgetTitle = "Search"
+
+// Filter narrows the tasks shown in the panes.
+// A query enclosed in slashes like /^fix.*bug/ is a regular expression,
+// otherwise it is a substring. Both are case-insensitive.
+type Filter struct {
+	Query string
+	re    *regexp.Regexp
+}
+
+// Search holds the filter of the search mode
+type Search struct {
+	Filter *Filter
+	// the filter restored when the search mode is canceled
+	saved *Filter
+}
+
+// NewFilter returns nil for an empty query
+func NewFilter(query string) (*Filter, error) {
+	if query == "" {
+		return nil, nil
+	}
+
+	pattern := regexp.QuoteMeta(query)
+	if len(query) > 2 && strings.HasPrefix(query, "/") && strings.HasSuffix(query, "/") {
+		pattern = query[1 : len(query)-1]
+	}
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regexp: %s", query)
+	}
+	return &Filter{Query: query, re: re}, nil
+}
+
+// Match reports whether the text, the contexts, the projects or the note of task matches the filter
+func (f *Filter) Match(task *todotxt.Task) bool {
+	if f == nil {
+		return true
+	}
+	fields := []string{task.Todo, tsk.Note(task)}
+	fields = append(fields, task.Contexts...)
+	fields = append(fields, task.Projects...)
+	for _, field := range fields {
+		if f.re.MatchString(field) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Filter) Apply(tasks db.TaskReferences) db.TaskReferences {
+	if f == nil {
+		return tasks
+	}
+	result := db.TaskReferences{}
+	for _, task := range tasks {
+		if f.Match(task) {
+			result = append(result, task)
+		}
+	}
+	return result
+}
+
+// paneTitle returns title followed by the query of the filter
+func (t *Tui) paneTitle(title string) string {
+	if t.Search.Filter == nil {
+		return title
+	}
+	return title + " " + tview.Escape("/"+t.Search.Filter.Query)
+}
+
+// setFilter applies filter to the panes
+func (t *Tui) setFilter(filter *Filter) {
+	t.Search.Filter = filter
+	t.redrawPanes()
+	t.updatePaneTitles()
+}
+
+func (t *Tui) startSearch() {
+	t.Search.saved = t.Search.Filter
+	t.InputWidget.SetTitle(searchWidgetTitle)
+	if t.Search.Filter != nil {
+		t.InputWidget.SetText(t.Search.Filter.Query)
+	}
+	t.Pages.ShowPage(inputField)
+	t.pushFocus(t.InputWidget.Box)
+	t.InputWidget.Mode = '/'
+}
+
+// searchChangedFunc filters the panes as the query is typed
+func (t *Tui) searchChangedFunc(text string) {
+	if t.InputWidget.Mode != '/' {
+		return
+	}
+	filter, err := NewFilter(text)
+	if err != nil {
+		// keep the last valid filter while typing a regexp
+		return
+	}
+	t.setFilter(filter)
+}
+
+func (t *Tui) cancelSearch() {
+	t.setFilter(t.Search.saved)
+}
+
+func (t *Tui) commitSearch(query string) error {
+	filter, err := NewFilter(query)
+	if err != nil {
+		return err
+	}
+	t.setFilter(filter)
+	if filter != nil {
+		count := 0
+		for _, pane := range t.Panes {
+			count += pane.GetRowCount()
+		}
+		t.Notify(fmt.Sprintf("%d tasks match", count), false)
+	}
+	return nil
+}
+
+// jumpMatch selects the next (or previous) matching task across the panes
+func (t *Tui) jumpMatch(forward bool) {
+	type position struct {
+		pane *TodoTable
+		row  int
+	}
+	matches := []position{}
+	current := -1
+	for _, pane := range t.Panes {
+		selected, _ := pane.GetSelection()
+		for row := 0; row < pane.GetRowCount(); row++ {
+			if pane.HasFocus() && row == selected {
+				current = len(matches)
+			}
+			matches = append(matches, position{pane, row})
+		}
+	}
+	if len(matches) == 0 {
+		t.Notify("No tasks match /"+t.Search.Filter.Query, true)
+		return
+	}
+
+	next := 0
+	if current >= 0 {
+		if forward {
+			next = (current + 1) % len(matches)
+		} else {
+			next = (current - 1 + len(matches)) % len(matches)
+		}
+	} else if !forward {
+		next = len(matches) - 1
+	}
+
+	m := matches[next]
+	if !m.pane.HasFocus() {
+		t.pushFocus(m.pane.Box)
+	}
+	m.pane.Select(m.row, 0)
+	t.Notify(fmt.Sprintf("match %d of %d", next+1, len(matches)), false)
+}
@@ -1,21 +1,35 @@
 package tui
 
 import (
+	"strings"
+
 	"github.com/1set/todotxt"
 	"github.com/rivo/tview"
+
+	db "github.com/apxxxxxxe/kanban.txt/internal/db"
 )
 
 func (t *Tui) setSelectedFunc() {
 	t.DaysTable.SetSelectionChangedFunc(t.daysTableSelectionChangedFunc)
 	t.ProjectPane.SetSelectionChangedFunc(t.projectPaneSelectionChangedFunc)
-	t.TodoPane.SetSelectionChangedFunc(t.todoPaneSelectionChangedFunc)
-	t.DoingPane.SetSelectionChangedFunc(t.doingPaneSelectionChangedFunc)
-	t.DonePane.SetSelectionChangedFunc(t.donePaneSelectionChangedFunc)
+	for _, pane := range t.Panes {
+		pane := pane
+		pane.SetSelectionChangedFunc(func(row, col int) { t.tableSelectionChangedFunc(pane, row, col) })
+	}
 }
 
+// reDrawProjects builds the projects of the selected day without saving,
+// not to wait for the lock of the data directory on every selection
 func (t *Tui) reDrawProjects() {
 	day, _ := t.getCurrentDay()
-	t.DB.RefreshProjects(day)
+	if err := t.DB.BuildProjects(day); err != nil {
+		t.Notify(err.Error(), true)
+	}
+	t.redrawPanes()
+}
+
+// redrawPanes shows the tasks of the current project matching the search filter
+func (t *Tui) redrawPanes() {
 	projects := t.DB.Projects
 
 	// TODO: 見た目との分離; 現在はProjectsByDateの各要素間でProjectとその並びが同一であることを前提にしている
@@ -23,9 +37,16 @@ func (t *Tui) reDrawProjects() {
 	if len(projects) > 0 {
 		project := projects[projectIndex]
 
-		t.TodoPane.ResetCell(project.TodoTasks)
-		t.DoingPane.ResetCell(project.DoingTasks)
-		t.DonePane.ResetCell(project.DoneTasks)
+		filter := t.Search.Filter
+		for i, pane := range t.Panes {
+			// a project just added by the input field has no tasks yet
+			var tasks db.TaskReferences
+			if i < len(project.Columns) {
+				tasks = project.Columns[i]
+			}
+			pane.ResetCell(filter.Apply(tasks))
+		}
+		t.updatePaneTitles()
 	}
 }
 
@@ -39,18 +60,6 @@ func (t *Tui) projectPaneSelectionChangedFunc(row, col int) {
 	}
 }
 
-func (t *Tui) todoPaneSelectionChangedFunc(row, col int) {
-	t.tableSelectionChangedFunc(t.TodoPane, row, col)
-}
-
-func (t *Tui) doingPaneSelectionChangedFunc(row, col int) {
-	t.tableSelectionChangedFunc(t.DoingPane, row, col)
-}
-
-func (t *Tui) donePaneSelectionChangedFunc(row, col int) {
-	t.tableSelectionChangedFunc(t.DonePane, row, col)
-}
-
 func (t *Tui) tableSelectionChangedFunc(table *TodoTable, row, col int) {
 	task, ok := table.GetCell(row, col).Reference.(*todotxt.Task)
 	if ok {
@@ -73,17 +82,88 @@ func (t *Tui) tableSelectionChangedFunc(table *TodoTable, row, col int) {
 			todoContexts,
 			todoCreatedDate,
 			todoMakedDoing,
+			todoTimeSpent,
 			todoDueDate,
 			todoCompletedDate,
 			todoRecurrence,
+			todoRecUntil,
+			todoRecCount,
+			todoRecSkip,
+			todoRecSnooze,
+			todoOccurrences,
+			todoID,
+			todoParent,
+			todoSubtasks,
+			todoDependencies,
+			todoBlockedBy,
 			todoNote,
 		}
 		description := [][]string{}
 		for _, field := range fields {
-			description = append(description, []string{field, tview.Escape(getTaskField(task, field))})
+			var value string
+			switch field {
+			case todoOccurrences:
+				value = t.occurrencesText(task)
+			case todoParent:
+				value = t.parentText(task)
+			case todoSubtasks:
+				value = t.subtasksText(task)
+			case todoBlockedBy:
+				value = blockersText(t.DB.Blockers(task))
+			case todoNote:
+				// 複数行の備考は1行にまとめて表示する
+				value = strings.ReplaceAll(getTaskField(t.DB, task, field), "\n", " ⏎ ")
+			default:
+				value = getTaskField(t.DB, task, field)
+			}
+			description = append(description, []string{field, tview.Escape(value)})
 		}
 		t.Descript(description)
 	} else {
 		t.Descript(nil)
 	}
 }
+
+func (t *Tui) occurrencesText(task *todotxt.Task) string {
+	dates, err := t.DB.Occurrences(task, previewOccurrences)
+	if err != nil {
+		return err.Error()
+	}
+	texts := []string{}
+	for _, date := range dates {
+		texts = append(texts, timeToStr(date))
+	}
+	return strings.Join(texts, " ")
+}
+
+func (t *Tui) parentText(task *todotxt.Task) string {
+	id := getTaskField(t.DB, task, todoParent)
+	if id == "" {
+		return ""
+	}
+	parent := t.DB.FindTask(id)
+	if parent == nil {
+		return id + " (not found)"
+	}
+	return id + " (" + parent.Todo + ")"
+}
+
+func (t *Tui) subtasksText(task *todotxt.Task) string {
+	texts := []string{}
+	for _, c := range t.DB.Children(task) {
+		if c.Completed {
+			texts = append(texts, "x "+c.Todo)
+		} else {
+			texts = append(texts, c.Todo)
+		}
+	}
+	return strings.Join(texts, ", ")
+}
+
+func blockersText(blockers db.TaskReferences) string {
+	texts := []string{}
+	for _, b := range blockers {
+		texts = append(texts, b.Todo)
+	}
+	return strings.Join(texts, ", ")
+}
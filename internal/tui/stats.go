@@ -0,0 +1,78 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/1set/todotxt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	db "github.com/apxxxxxxe/kanban.txt/internal/db"
+)
+
+const (
+	// the number of the weeks of the throughput in the stats page
+	statsWeeks = 8
+	// the width of the longest bar of the throughput
+	statsBarWidth = 40
+)
+
+func percentilesText(p db.Percentiles) string {
+	if p.Count == 0 {
+		return "no data"
+	}
+	return fmt.Sprintf("p50 %dd  p85 %dd  p95 %dd  (%d tasks)", p.P50, p.P85, p.P95, p.Count)
+}
+
+// showStats shows the flow metrics of the current project
+func (t *Tui) showStats() {
+	p := t.ProjectPane.GetCurrentProject()
+	if p == nil {
+		p = &db.Project{ProjectName: db.AllTasks}
+	}
+	m, err := t.DB.FlowMetrics(p, time.Now(), statsWeeks)
+	if err != nil {
+		t.Notify(err.Error(), true)
+		return
+	}
+
+	text := "[#a0a0a0::b]" + tview.Escape(m.ProjectName) + "[-::-]\n\n"
+	text += "Lead time   " + percentilesText(m.LeadTime) + "\n"
+	text += "Cycle time  " + percentilesText(m.CycleTime) + "\n\n"
+
+	max := statsBarWidth
+	for _, w := range m.Throughput {
+		if w.Completed > max {
+			max = w.Completed
+		}
+	}
+	text += "[#a0a0a0::b]Weekly throughput[-::-]\n"
+	for _, w := range m.Throughput {
+		bar := strings.Repeat("█", w.Completed*statsBarWidth/max)
+		text += fmt.Sprintf("  %s %s %d\n", w.Week.Format(todotxt.DateLayout), bar, w.Completed)
+	}
+
+	text += "\n[#a0a0a0::b]Aging[-::-]\n"
+	if len(m.Aging) == 0 {
+		text += "  no tasks in progress\n"
+	}
+	for _, a := range m.Aging {
+		text += fmt.Sprintf("  %3dd %s\n", a.Days, tview.Escape(a.Task.Todo))
+	}
+
+	text += "\nPress Esc or q to close"
+	t.StatsWidget.SetText(text).ScrollToBeginning()
+	t.Pages.ShowPage(statsPage)
+	t.pushFocus(t.StatsWidget.Box)
+}
+
+func (t *Tui) statsWidgetInputCaptureFunc(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() == tcell.KeyEscape || event.Rune() == 'q' {
+		t.Pages.HidePage(statsPage)
+		t.popFocus()
+		return nil
+	}
+	return event
+}
@@ -0,0 +1,79 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/1set/todotxt"
+
+	db "github.com/apxxxxxxe/kanban.txt/internal/db"
+)
+
+const (
+	subtaskCompleteAll  = "Complete all"
+	subtaskCompleteThis = "Only this task"
+	confirmCancel       = "Cancel"
+)
+
+// confirm shows text with buttons and calls done with the label of the pressed one
+func (t *Tui) confirm(text string, buttons []string, done func(label string)) {
+	focus := t.App.GetFocus()
+	t.ConfirmWidget.ClearButtons()
+	t.ConfirmWidget.SetText(text).AddButtons(buttons).SetFocus(0)
+	t.ConfirmWidget.SetDoneFunc(func(_ int, label string) {
+		t.Pages.HidePage(confirmModal)
+		t.App.SetFocus(focus)
+		done(label)
+	})
+	t.Pages.ShowPage(confirmModal)
+	t.App.SetFocus(t.ConfirmWidget)
+}
+
+// startAddSubtask opens the input field to add a subtask to the selected task of pane
+func (t *Tui) startAddSubtask(pane *TodoTable) {
+	if pane.GetRowCount() == 0 {
+		t.Notify("No task selected", true)
+		return
+	}
+	t.EditingCell = pane.GetCell(pane.GetSelection())
+	t.InputWidget.SetTitle("New Subtask")
+	t.Pages.ShowPage(inputField)
+	t.pushFocus(t.InputWidget.Box)
+	t.InputWidget.Mode = 's'
+}
+
+// completeTask moves task to the Done column, asking what to do with its open subtasks
+func (t *Tui) completeTask(task *todotxt.Task) {
+	open := db.TaskReferences{}
+	for _, c := range t.DB.Children(task) {
+		if !c.Completed {
+			open = append(open, c)
+		}
+	}
+
+	complete := func(tasks db.TaskReferences) {
+		pane := t.focusedPane()
+		t.recordHistory()
+		for _, task := range tasks {
+			t.DB.MoveTask(task, t.DB.DoneColumn(), t.getSelectingDate())
+		}
+		t.refreshProjects()
+		if pane != nil {
+			pane.AdjustSelection()
+		}
+	}
+
+	if len(open) == 0 {
+		complete(db.TaskReferences{task})
+		return
+	}
+
+	text := fmt.Sprintf("%q has %d open subtasks.", task.Todo, len(open))
+	t.confirm(text, []string{subtaskCompleteAll, subtaskCompleteThis, confirmCancel}, func(label string) {
+		switch label {
+		case subtaskCompleteAll:
+			complete(append(db.TaskReferences{task}, open...))
+		case subtaskCompleteThis:
+			complete(db.TaskReferences{task})
+		}
+	})
+}
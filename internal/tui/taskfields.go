@@ -1,9 +1,12 @@
 package tui
 
 import (
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/1set/todotxt"
+	db "github.com/apxxxxxxe/kanban.txt/internal/db"
 	"github.com/apxxxxxxe/kanban.txt/internal/task"
 	"github.com/google/uuid"
 )
@@ -17,26 +20,32 @@ const (
 	todoCompletedDate = "CompletedDate"
 	todoCreatedDate   = "CreatedDate"
 	todoRecurrence    = "Recurrence"
+	todoRecUntil      = "RecurrenceUntil"
+	todoRecCount      = "RecurrenceCount"
+	todoRecSkip       = "SkippedOccurrences"
+	todoRecSnooze     = "SnoozedTo"
+	todoOccurrences   = "NextOccurrences"
 	todoNote          = "Note"
 	todoMakedDoing    = "StartDoingDate"
+	todoID            = "ID"
+	todoParent        = "Parent"
+	todoSubtasks      = "Subtasks"
+	todoDependencies  = "Dependencies"
+	todoBlockedBy     = "BlockedBy"
+	todoTimeSpent     = "TimeSpent"
 )
 
-func getTaskField(t *todotxt.Task, field string) string {
+func getTaskField(d *db.Database, t *todotxt.Task, field string) string {
 	switch field {
 	case todoProjects:
-		if len(t.Projects) == 0 {
-			return ""
-		}
-		return t.Projects[0]
+		return strings.Join(t.Projects, " ")
 	case todoPriority:
 		return t.Priority
 	case todoTitle:
 		return t.Todo
 	case todoContexts:
-		if len(t.Contexts) == 0 {
-			return ""
-		}
-		return t.Contexts[0]
+		// the contexts of the columns are the state of the board, not edited as contexts
+		return strings.Join(d.TaskContexts(t), " ")
 	case todoDueDate:
 		return timeToStr(t.DueDate)
 	case todoCompletedDate:
@@ -45,59 +54,162 @@ func getTaskField(t *todotxt.Task, field string) string {
 		return timeToStr(t.CreatedDate)
 	case todoRecurrence:
 		return t.AdditionalTags[task.KeyRec]
+	case todoRecUntil:
+		return t.AdditionalTags[task.KeyRecUntil]
+	case todoRecCount:
+		return t.AdditionalTags[task.KeyRecCount]
+	case todoRecSkip:
+		return t.AdditionalTags[task.KeyRecSkip]
+	case todoRecSnooze:
+		return t.AdditionalTags[task.KeyRecSnooze]
 	case todoNote:
-		return t.AdditionalTags[task.KeyNote]
+		return task.Note(t)
 	case todoMakedDoing:
 		return t.AdditionalTags[task.KeyStartDoing]
+	case todoID:
+		return t.AdditionalTags[task.KeyID]
+	case todoParent:
+		return t.AdditionalTags[task.KeyParent]
+	case todoDependencies:
+		return t.AdditionalTags[task.KeyDep]
+	case todoTimeSpent:
+		if _, ok := t.AdditionalTags[task.KeyClock]; !ok {
+			return ""
+		}
+		if _, err := task.Stints(t); err != nil {
+			return err.Error()
+		}
+		spent := task.FormatDuration(task.Elapsed(t, time.Now()))
+		if task.IsClockRunning(t) {
+			spent += " (running)"
+		}
+		return spent
 	default:
 		panic("invalid field: " + field)
 	}
 }
 
-func setTaskField(t *todotxt.Task, field, value string) {
+func setTaskField(d *db.Database, t *todotxt.Task, field, value string) error {
+	var err error
 	switch field {
 	case todoProjects:
+		t.Projects = splitNames(value, "+")
 		if len(t.Projects) == 0 {
-			t.Projects = []string{value}
-		} else {
-			t.Projects[0] = value
+			t.Projects = []string{db.NoProject}
 		}
 	case todoPriority:
 		t.Priority = value
 	case todoTitle:
 		t.Todo = value
 	case todoContexts:
-		if len(t.Contexts) == 0 {
-			t.Contexts = []string{value}
-		} else {
-			t.Contexts[0] = value
+		d.SetTaskContexts(t, splitNames(value, "@"))
+	case todoDueDate, todoCompletedDate, todoCreatedDate:
+		// 入力を誤っても元の日付を消さないよう、解析できたときだけ代入する
+		date, err := strToTime(value)
+		if err != nil {
+			return err
+		}
+		switch field {
+		case todoDueDate:
+			t.DueDate = date
+		case todoCompletedDate:
+			t.CompletedDate = date
+		case todoCreatedDate:
+			t.CreatedDate = date
 		}
-	case todoDueDate:
-		t.DueDate = strToTime(value)
-	case todoCompletedDate:
-		t.CompletedDate = strToTime(value)
-	case todoCreatedDate:
-		t.CreatedDate = strToTime(value)
 	case todoRecurrence:
+		if value == "" {
+			delete(t.AdditionalTags, task.KeyRec)
+			return nil
+		}
+		if _, err := task.ParseRecurrenceRule(value); err != nil {
+			return err
+		}
 		if t.AdditionalTags == nil {
 			t.AdditionalTags = map[string]string{}
 		}
 		t.AdditionalTags[task.KeyRec] = value
 		key := uuid.New().String()
 		t.AdditionalTags[task.KeyRecID] = key
-	case todoNote:
+	case todoRecUntil, todoRecCount, todoRecSkip, todoRecSnooze:
+		key := map[string]string{
+			todoRecUntil:  task.KeyRecUntil,
+			todoRecCount:  task.KeyRecCount,
+			todoRecSkip:   task.KeyRecSkip,
+			todoRecSnooze: task.KeyRecSnooze,
+		}[field]
+		if value == "" {
+			delete(t.AdditionalTags, key)
+			return nil
+		}
+		tmp := todotxt.Task{AdditionalTags: map[string]string{key: value}}
+		if err := task.ValidateRecurrence(&tmp); err != nil {
+			return err
+		}
 		if t.AdditionalTags == nil {
 			t.AdditionalTags = map[string]string{}
 		}
-		t.AdditionalTags[task.KeyNote] = value
+		t.AdditionalTags[key] = value
+	case todoNote:
+		task.SetNote(t, value)
 	case todoMakedDoing:
 		if t.AdditionalTags == nil {
 			t.AdditionalTags = map[string]string{}
 		}
 		t.AdditionalTags[task.KeyStartDoing] = value
+	case todoParent:
+		if value == "" {
+			delete(t.AdditionalTags, task.KeyParent)
+			return nil
+		}
+		if strings.ContainsAny(value, " :") {
+			return errors.New("invalid id: " + value)
+		}
+		if value == t.AdditionalTags[task.KeyID] {
+			return errors.New("a task cannot be its own parent")
+		}
+		if t.AdditionalTags == nil {
+			t.AdditionalTags = map[string]string{}
+		}
+		t.AdditionalTags[task.KeyParent] = value
+	case todoDependencies:
+		// "a b" and "a,b" are both written as dep:a,b
+		ids := strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+		if len(ids) == 0 {
+			delete(t.AdditionalTags, task.KeyDep)
+			return nil
+		}
+		if strings.Contains(value, ":") {
+			return errors.New("invalid id: " + value)
+		}
+		if t.AdditionalTags == nil {
+			t.AdditionalTags = map[string]string{}
+		}
+		t.AdditionalTags[task.KeyDep] = strings.Join(ids, ",")
 	default:
 		panic("invalid field: " + field)
 	}
+	return err
+}
+
+// splitNames splits the space separated names of projects or contexts,
+// which may be written with the prefix like "+work +home"
+func splitNames(value, prefix string) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, name := range strings.Fields(value) {
+		name = strings.TrimPrefix(name, prefix)
+		if name != "" && !seen[name] {
+			names = append(names, name)
+			seen[name] = true
+		}
+	}
+	return names
+}
+
+// isReadOnlyField reports whether field is computed and cannot be edited
+func isReadOnlyField(field string) bool {
+	return field == todoOccurrences || field == todoID || field == todoSubtasks || field == todoBlockedBy || field == todoTimeSpent
 }
 
 func timeToStr(t time.Time) string {
@@ -107,13 +219,9 @@ func timeToStr(t time.Time) string {
 	return t.Format(todotxt.DateLayout)
 }
 
-func strToTime(s string) time.Time {
+func strToTime(s string) (time.Time, error) {
 	if s == "" {
-		return time.Time{}
-	}
-	t, err := time.Parse(todotxt.DateLayout, s)
-	if err != nil {
-		panic(err)
+		return time.Time{}, nil
 	}
-	return t
+	return time.Parse(todotxt.DateLayout, s)
 }
@@ -1,6 +1,9 @@
 package tui
 
 import (
+	"fmt"
+	"time"
+
 	todo "github.com/1set/todotxt"
 	"github.com/gdamore/tcell/v2"
 	"github.com/pkg/errors"
@@ -25,6 +28,8 @@ const (
 
 type TodoTable struct {
 	*tview.Table
+	Color *db.ColorConfig
+	DB    *db.Database
 }
 
 var ErrFeedNotExist = errors.Errorf("Feed Not Exist")
@@ -74,11 +79,33 @@ func (t *TodoTable) setCell(f *todo.Task) *tview.TableCell {
 	if _, ok := f.AdditionalTags[tsk.KeyRec]; ok {
 		if _, ok := f.AdditionalTags[tsk.KeyRecID]; !ok {
 			text = "󰀦 " + text
+		} else if err := tsk.ValidateRecurrence(f); err != nil {
+			text = "󰀦 " + text
 		}
 		text = " " + text
 	}
 
-	cell := tview.NewTableCell(tview.Escape(text)).SetReference(f)
+	if !f.Completed && t.DB.IsBlocked(f) {
+		text = "󰌾 " + text
+	}
+
+	if done, total := t.DB.Progress(f); total > 0 {
+		text += fmt.Sprintf(" [%d/%d]", done, total)
+	}
+
+	text = tview.Escape(text)
+	for _, c := range f.Contexts {
+		if c == "doing" {
+			continue
+		}
+		if isPaintable(t.Color, c) {
+			text += fmt.Sprintf(" [%s]@%s[-]", colorToHex(nameColor(t.Color, c)), tview.Escape(c))
+		} else {
+			text += " @" + tview.Escape(c)
+		}
+	}
+
+	cell := tview.NewTableCell(text).SetReference(f)
 
 	if f.HasPriority() {
 		switch f.Priority {
@@ -101,6 +128,13 @@ func (t *TodoTable) setCell(f *todo.Task) *tview.TableCell {
 
 	t.SetCell(targetRow, 0, cell)
 
+	// 経過時間は刻々と変わるため、セルの文字列で選択を復元できるよう別の列に表示する
+	timer := ""
+	if tsk.IsClockRunning(f) {
+		timer = "󰔛 " + tsk.FormatDuration(tsk.Elapsed(f, time.Now()))
+	}
+	t.SetCell(targetRow, 1, tview.NewTableCell(timer).SetReference(f).SetTextColor(cell.Color))
+
 	// SelectionChangedFuncを発火する
 	if maxRow == 0 {
 		t.Select(t.GetSelection())
@@ -6,47 +6,70 @@ import (
 	"github.com/apxxxxxxe/kanban.txt/pkg/util"
 	"github.com/pkg/errors"
 	"github.com/rivo/tview"
+	"strings"
 	"time"
 )
 
 type Tui struct {
-	Config             *db.Config
-	DB                 *db.Database
-	App                *tview.Application
-	Pages              *tview.Pages
-	DaysTable          *tview.Table
-	ProjectPane        *ProjectTable
-	TodoPane           *TodoTable
-	DoingPane          *TodoTable
-	DonePane           *TodoTable
+	Config      *db.Config
+	DB          *db.Database
+	App         *tview.Application
+	Pages       *tview.Pages
+	DaysTable   *tview.Table
+	ProjectPane *ProjectTable
+	// the panes of the columns of the board from left to right
+	Panes              []*TodoTable
 	DescriptionWidget  *tview.Table
 	InfoWidget         *tview.TextView
 	HelpWidget         *tview.TextView
 	InputWidget        *InputBox
 	ColorWidget        *tview.Table
+	ConflictWidget     *tview.Modal
+	ConfirmWidget      *tview.Modal
+	KeymapWidget       *tview.TextView
+	StatsWidget        *tview.TextView
+	ChartWidget        *tview.TextView
+	ArchiveWidget      *tview.Table
+	Keymap             *Keymap
 	FocusStack         []*tview.Box
+	History            *History
+	Search             *Search
 	EditingCell        *tview.TableCell
 	ConfirmationStatus int
 	CurrentLeftTable   int
 	IsLoading          bool
+	// the index of the pane whose neighbors are hinted in the pane titles, -1 if none
+	HintedPane int
 }
 
 const (
 	descriptionField       = "descPopup"
 	inputField             = "InputPopup"
 	colorTable             = "ColorTablePopup"
+	conflictModal          = "ConflictModal"
+	confirmModal           = "ConfirmModal"
 	mainPage               = "MainPage"
 	keymapPage             = "KeymapPage"
+	statsPage              = "StatsPage"
+	chartPage              = "ChartPage"
+	archivePage            = "ArchivePage"
 	projectPaneTitle       = "Project"
-	todoPaneTitle          = "Todo"
-	doingPaneTitle         = "Doing"
-	donePaneTitle          = "Done"
 	descriptionWidgetTitle = "Description"
 	helpWidgetTitle        = "Help"
 	infoWidgetTitle        = "Info"
 	colorWidgetTitle       = "Color"
+	keymapWidgetTitle      = "Keymap"
+	statsWidgetTitle       = "Stats"
+	chartWidgetTitle       = "Charts"
+	archiveWidgetTitle     = "Archive"
+	conflictReload         = "Reload from disk"
+	conflictOverwrite      = "Keep mine"
+	quitAnyway             = "Quit anyway"
 )
 
+// the number of the next occurrences shown in the description
+const previewOccurrences = 5
+
 const (
 	enumTodoPane = iota
 	enumDoingPane
@@ -54,30 +77,49 @@ const (
 
 var ErrImportFileNotFound = errors.Errorf("todo.txt not found")
 
-func NewTui() *Tui {
+func NewTui() (*Tui, error) {
 	tview.Styles.ContrastBackgroundColor = tview.Styles.PrimitiveBackgroundColor
 
+	config, err := db.LoadOrNewConfig()
+	if err != nil {
+		return nil, err
+	}
+	database := &db.Database{Columns: config.Columns, KeepDoneTasks: config.KeepDoneTasks, KeepDoneDays: config.KeepDoneDays}
 	tui := &Tui{
-		Config:             db.LoadOrNewConfig(),
-		DB:                 &db.Database{},
+		Config:             config,
+		DB:                 database,
 		App:                tview.NewApplication(),
 		Pages:              tview.NewPages(),
 		DaysTable:          tview.NewTable().SetBorders(false).SetSelectable(false, true),
-		ProjectPane:        &ProjectTable{newTable(projectPaneTitle)},
-		TodoPane:           &TodoTable{newTable(todoPaneTitle)},
-		DoingPane:          &TodoTable{newTable(doingPaneTitle)},
-		DonePane:           &TodoTable{newTable(donePaneTitle)},
+		ProjectPane:        &ProjectTable{Table: newTable(projectPaneTitle), Color: config.Color},
 		DescriptionWidget:  newTable(descriptionWidgetTitle),
 		InfoWidget:         newTextView(infoWidgetTitle),
 		HelpWidget:         newTextView(helpWidgetTitle).SetTextAlign(1).SetDynamicColors(true),
 		InputWidget:        &InputBox{InputField: newInputField(), Mode: 0},
+		ConflictWidget:     newConflictModal(),
+		ConfirmWidget:      tview.NewModal(),
+		KeymapWidget:       newTextView(keymapWidgetTitle),
+		StatsWidget:        newTextView(statsWidgetTitle).SetDynamicColors(true),
+		ChartWidget:        newTextView(chartWidgetTitle).SetDynamicColors(true),
+		ArchiveWidget:      newTable(archiveWidgetTitle),
+		ColorWidget:        newTable(colorWidgetTitle),
 		FocusStack:         []*tview.Box{},
+		History:            &History{},
+		Search:             &Search{},
 		EditingCell:        nil,
 		ConfirmationStatus: defaultStatus,
 		CurrentLeftTable:   enumTodoPane,
+		HintedPane:         -1,
 		IsLoading:          false,
 	}
 
+	panesFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
+	for _, c := range database.GetColumns() {
+		pane := &TodoTable{Table: newTable(c.Name), Color: config.Color, DB: database}
+		tui.Panes = append(tui.Panes, pane)
+		panesFlex.AddItem(pane, 0, 1, false)
+	}
+
 	const NonZero = 1
 	daysLabels := []string{}
 	now := time.Now()
@@ -95,11 +137,7 @@ func NewTui() *Tui {
 			AddItem(tui.ProjectPane, 0, 1, false).
 			AddItem(
 				tview.NewFlex().SetDirection(tview.FlexRow).
-					AddItem(tview.NewFlex().SetDirection(tview.FlexColumn).
-						AddItem(tui.TodoPane, 0, 1, false).
-						AddItem(tui.DoingPane, 0, 1, false).
-						AddItem(tui.DonePane, 0, 1, false),
-						0, 3, false).
+					AddItem(panesFlex, 0, 3, false).
 					AddItem(tview.NewFlex().SetDirection(tview.FlexColumn).
 						AddItem(tui.DescriptionWidget, 0, 2, false).
 						AddItem(tui.InfoWidget, 0, 1, false),
@@ -113,9 +151,24 @@ func NewTui() *Tui {
 			AddItem(nil, 0, 1, false), 40, 1, false).
 		AddItem(nil, 0, 1, false)
 
+	colorFlex := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(tui.ColorWidget, colorChoices+4, 1, false).
+			AddItem(nil, 0, 1, false), 40, 1, false).
+		AddItem(nil, 0, 1, false)
+
 	tui.Pages.
 		AddPage(mainPage, mainFlex, true, true).
-		AddPage(inputField, inputFlex, true, false)
+		AddPage(inputField, inputFlex, true, false).
+		AddPage(conflictModal, tui.ConflictWidget, true, false).
+		AddPage(confirmModal, tui.ConfirmWidget, true, false).
+		AddPage(keymapPage, tui.KeymapWidget, true, false).
+		AddPage(statsPage, tui.StatsWidget, true, false).
+		AddPage(chartPage, tui.ChartWidget, true, false).
+		AddPage(archivePage, tui.ArchiveWidget, true, false).
+		AddPage(colorTable, colorFlex, true, false)
 
 	tui.App.SetRoot(tui.Pages, true)
 
@@ -124,7 +177,7 @@ func NewTui() *Tui {
 	tui.setFocusedFunc()
 	tui.setBlurFunc()
 
-	return tui
+	return tui, nil
 }
 
 func (t *Tui) pushFocus(b *tview.Box) {
@@ -167,10 +220,15 @@ func (t *Tui) getCurrentDay() (int, int) {
 	return col - db.DayCount/2, col
 }
 
-func (t *Tui) refreshProjects() {
-  day, _ := t.getCurrentDay()
+// refreshProjects reports whether the projects were refreshed and saved
+func (t *Tui) refreshProjects() bool {
+	day, _ := t.getCurrentDay()
+	ok := true
 	if err := t.DB.RefreshProjects(day); err != nil {
-		t.Notify(err.Error(), true)
+		t.handleDBError(err)
+		ok = false
+	} else if cycle := t.DB.DependencyCycle; cycle != nil {
+		t.Notify("Dependency cycle: "+strings.Join(cycle, " -> "), true)
 	}
 	row, col := t.ProjectPane.GetSelection()
 	t.ProjectPane.ResetCell(t.DB.Projects)
@@ -182,6 +240,24 @@ func (t *Tui) refreshProjects() {
 	}
 	t.ProjectPane.Select(row, col)
 	t.App.SetFocus(t.App.GetFocus())
+	return ok
+}
+
+// quit stops the app, trying to save the changes left unsaved by a locked data directory first
+func (t *Tui) quit() {
+	if !t.DB.HasUnsavedChanges() || t.refreshProjects() {
+		t.App.Stop()
+		return
+	}
+	if t.ConflictWidget.HasFocus() {
+		// 競合の解決を先に行う
+		return
+	}
+	t.confirm("The changes are not saved. Quit anyway?", []string{quitAnyway, confirmCancel}, func(label string) {
+		if label == quitAnyway {
+			t.App.Stop()
+		}
+	})
 }
 
 func (t *Tui) Notify(m string, red bool) {
@@ -204,6 +280,22 @@ func (t *Tui) Help(help [][]string) {
 }
 
 func (t *Tui) Run() error {
+	keymap, err := NewKeymap(t.Config.Keys)
+	if err != nil {
+		return err
+	}
+	t.Keymap = keymap
+
+	if err := db.ValidateColumns(t.Config.Columns); err != nil {
+		return err
+	}
+
+	views, err := db.ParseViews(t.Config.Views)
+	if err != nil {
+		return err
+	}
+	t.DB.Views = views
+
 	if err := t.DB.LoadData(); err != nil {
 		return err
 	}
@@ -214,12 +306,16 @@ func (t *Tui) Run() error {
 	t.ProjectPane.ResetCell(t.DB.Projects)
 	t.ProjectPane.Select(0, 0) // len(t.DB.Projects) is usually > 0
 
-	t.doingPaneBlurFunc()
-	t.donePaneBlurFunc()
+	for _, pane := range t.Panes[1:] {
+		t.tableBlurFunc(pane.Table)
+	}
 	t.descriptionWidgetBlurFunc()
 
 	t.pushFocus(t.ProjectPane.Box)
 
+	t.watchDataFiles()
+	t.tickClock()
+
 	if err := t.App.Run(); err != nil {
 		t.App.Stop()
 		return err
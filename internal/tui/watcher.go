@@ -0,0 +1,127 @@
+package tui
+
+import (
+	"errors"
+	"time"
+
+	db "github.com/apxxxxxxe/kanban.txt/internal/db"
+	tsk "github.com/apxxxxxxe/kanban.txt/internal/task"
+	"github.com/rivo/tview"
+)
+
+const (
+	watchInterval = time.Second
+	// the interval to update the timers on the cards
+	clockInterval = 30 * time.Second
+)
+
+// watchDataFiles polls the data files and reloads them when another program changes them
+func (t *Tui) watchDataFiles() {
+	go func() {
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			t.App.QueueUpdate(t.reloadIfModified)
+		}
+	}()
+}
+
+// tickClock redraws the running timers on the cards
+func (t *Tui) tickClock() {
+	go func() {
+		ticker := time.NewTicker(clockInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			t.App.QueueUpdateDraw(t.redrawTimers)
+		}
+	}()
+}
+
+func (t *Tui) redrawTimers() {
+	// the cells being edited would be replaced
+	if t.InputWidget.HasFocus() || t.DescriptionWidget.HasFocus() {
+		return
+	}
+	for _, task := range t.DB.LivingTasks {
+		if tsk.IsClockRunning(task) {
+			t.redrawPanes()
+			return
+		}
+	}
+}
+
+func (t *Tui) reloadIfModified() {
+	if !t.DB.IsModifiedOnDisk() {
+		return
+	}
+
+	// the task being edited would be replaced by the reloaded one
+	if t.InputWidget.HasFocus() || t.DescriptionWidget.HasFocus() || t.ConflictWidget.HasFocus() || t.ConfirmWidget.HasFocus() || t.ColorWidget.HasFocus() {
+		return
+	}
+
+	pane := t.focusedPane()
+	cellText := ""
+	if pane != nil && pane.GetRowCount() > 0 {
+		cellText = pane.GetCell(pane.GetSelection()).Text
+	}
+
+	if !t.refreshProjects() {
+		t.App.ForceDraw()
+		return
+	}
+
+	if pane != nil {
+		pane.AdjustSelection()
+		if cellText != "" {
+			pane.SelectByText(cellText)
+		}
+	}
+	t.Notify("Reloaded todo.txt changed by another program", false)
+	t.App.ForceDraw()
+}
+
+func newConflictModal() *tview.Modal {
+	return tview.NewModal().
+		SetText("todo.txt was changed by another program while you were editing the same task.").
+		AddButtons([]string{conflictReload, conflictOverwrite})
+}
+
+// handleDBError shows the conflict dialog for db.ErrConflict and notifies other errors
+func (t *Tui) handleDBError(err error) {
+	if errors.Is(err, db.ErrLocked) {
+		// the changes are kept in memory and saved by the next refresh
+		t.Notify(err.Error()+"; changes are not saved yet", true)
+		return
+	}
+	if !errors.Is(err, db.ErrConflict) {
+		t.Notify(err.Error(), true)
+		return
+	}
+	if t.ConflictWidget.HasFocus() {
+		return
+	}
+
+	focus := t.App.GetFocus()
+	t.ConflictWidget.SetDoneFunc(func(_ int, label string) {
+		t.Pages.HidePage(conflictModal)
+		t.App.SetFocus(focus)
+
+		var err error
+		switch label {
+		case conflictReload:
+			err = t.DB.ReloadFromDisk()
+		case conflictOverwrite:
+			err = t.DB.SaveData()
+		default:
+			return
+		}
+		if err != nil {
+			t.Notify(err.Error(), true)
+			return
+		}
+		t.refreshProjects()
+	})
+	t.Pages.ShowPage(conflictModal)
+	t.App.SetFocus(t.ConflictWidget)
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 
+	"github.com/apxxxxxxe/kanban.txt/internal/cli"
 	"github.com/apxxxxxxe/kanban.txt/internal/db"
 	"github.com/apxxxxxxe/kanban.txt/internal/tui"
 )
@@ -14,12 +16,36 @@ func run() int {
 		dataPath string
 	)
 	flag.StringVar(&dataPath, "data-path", "", "path of the data directory")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] [command]\n", os.Args[0])
+		flag.PrintDefaults()
+		cli.PrintUsage(flag.CommandLine.Output())
+	}
 	flag.Parse()
 	if dataPath != "" {
 		db.CustomDataPath = dataPath
 	}
 
-	if err := tui.NewTui().Run(); err != nil {
+	if flag.NArg() > 0 {
+		if err := cli.Run(flag.Args()); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return 0
+			}
+			fmt.Fprintln(os.Stderr, err)
+			if errors.Is(err, cli.ErrUnknownCommand) {
+				flag.Usage()
+			}
+			return 1
+		}
+		return 0
+	}
+
+	t, err := tui.NewTui()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := t.Run(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
 	}
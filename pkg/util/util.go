@@ -6,12 +6,12 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
-  "time"
 	"path/filepath"
+	"time"
 )
 
 func RemoveClockTime(date time.Time) time.Time {
-  return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
 }
 
 func IsFile(filename string) bool {
@@ -29,15 +29,105 @@ func IsDir(path string) bool {
 }
 
 func SaveBytes(data []byte, path string) error {
-	file, err := os.Create(path)
+	return WriteFileAtomic(path, data, 0644)
+}
+
+// FileContent is a pair of a file path and the data to be written to it.
+type FileContent struct {
+	Path string
+	Data []byte
+}
+
+// WriteFileAtomic replaces the file at path with data
+// so that the file never becomes half-written even if the process is killed.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return WriteFilesAtomic([]FileContent{{Path: path, Data: data}}, perm)
+}
+
+// WriteFilesAtomic writes every file to a temporary file and fsyncs it,
+// then renames them to their paths in the order of files only after all of them have been written.
+// Each file is replaced atomically, but the batch is not:
+// if a rename fails or the process dies between the renames,
+// the files renamed before it keep their new data and the rest keep their old data.
+// perm is used for files which do not exist yet.
+func WriteFilesAtomic(files []FileContent, perm os.FileMode) error {
+	tmpPaths := make([]string, 0, len(files))
+	cleanup := func() {
+		for _, p := range tmpPaths {
+			os.Remove(p)
+		}
+	}
+
+	for _, f := range files {
+		tmpPath, err := writeTempFile(f, perm)
+		if err != nil {
+			cleanup()
+			return err
+		}
+		tmpPaths = append(tmpPaths, tmpPath)
+	}
+
+	for i, f := range files {
+		if err := os.Rename(tmpPaths[i], f.Path); err != nil {
+			cleanup()
+			return err
+		}
+	}
+
+	dirs := map[string]bool{}
+	for _, f := range files {
+		dirs[filepath.Dir(f.Path)] = true
+	}
+	for dir := range dirs {
+		if err := syncDir(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTempFile(f FileContent, perm os.FileMode) (string, error) {
+	if info, err := os.Stat(f.Path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	fp, err := os.CreateTemp(filepath.Dir(f.Path), "."+filepath.Base(f.Path)+".tmp*")
 	if err != nil {
-		panic(err)
+		return "", err
 	}
-	defer file.Close()
-	_, err = file.Write(data)
+	tmpPath := fp.Name()
+
+	if _, err := fp.Write(f.Data); err != nil {
+		fp.Close()
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := fp.Chmod(perm); err != nil {
+		fp.Close()
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := fp.Sync(); err != nil {
+		fp.Close()
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := fp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	return tmpPath, nil
+}
+
+// syncDir makes renames in dir durable.
+// Some platforms cannot fsync a directory, so the error of Sync is ignored.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
 	if err != nil {
-		panic(err)
+		return err
 	}
+	defer d.Close()
+	d.Sync()
 	return nil
 }
 